@@ -0,0 +1,37 @@
+package adapters
+
+import (
+	"io"
+	"net/http"
+)
+
+// CountingBody — request body wrapper counting bytes read by the handler
+type CountingBody struct {
+	io.ReadCloser
+	N int64
+}
+
+func (b *CountingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.N += int64(n)
+	return n, err
+}
+
+// CountBody — replaces r.Body with a counting wrapper and returns it, nil for empty bodies
+func CountBody(r *http.Request) *CountingBody {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	body := &CountingBody{ReadCloser: r.Body}
+	r.Body = body
+	return body
+}
+
+// RequestSize — bytes read from the body, or the declared length when the handler read less
+func RequestSize(r *http.Request, body *CountingBody) int64 {
+	var n int64
+	if body != nil {
+		n = body.N
+	}
+	return max(n, r.ContentLength)
+}
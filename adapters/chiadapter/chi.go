@@ -0,0 +1,29 @@
+// Package chiadapter records requests served by a go-chi/chi router.
+package chiadapter
+
+import (
+	"net/http"
+
+	"github.com/aurieli333/goapimon/adapters"
+	"github.com/aurieli333/goapimon/monitor"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Middleware — chi middleware labelling requests with the matched route pattern, e.g. /users/{id}
+//
+//	r := chi.NewRouter()
+//	r.Use(chiadapter.Middleware(goapimon.Monitor))
+func Middleware(m *monitor.Monitor) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return adapters.MiddlewareHTTP(m, next, routePattern)
+	}
+}
+
+// routePattern is read after the handler ran, when chi has finished routing
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
@@ -0,0 +1,16 @@
+package adapters
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientIP — caller address of a request, taken from RemoteAddr.
+// Forwarded headers are not trusted, so behind a reverse proxy this is the proxy.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,59 @@
+// Package echoadapter records requests served by labstack/echo.
+package echoadapter
+
+import (
+	"time"
+
+	"github.com/aurieli333/goapimon/adapters"
+	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/monitor"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Middleware — Echo middleware labelling requests with the route path, e.g. /users/:id
+//
+//	e := echo.New()
+//	e.Use(echoadapter.Middleware(goapimon.Monitor))
+func Middleware(m *monitor.Monitor) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if !m.Monitored(req.Method, req.URL.Path) {
+				return next(c)
+			}
+
+			body := adapters.CountBody(req)
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Let Echo render the error now, so the final status is recorded
+				c.Error(err)
+			}
+			elapsed := time.Since(start)
+
+			// Empty when no route matched
+			path := c.Path()
+			if path == "" {
+				path = model.UnmatchedRoute
+			}
+
+			res := c.Response()
+			m.Record(model.RequestRecord{
+				Timestamp: start,
+				Duration:  elapsed,
+				Status:    res.Status,
+				Method:    req.Method,
+				Path:      path,
+				TraceID:   adapters.TraceID(req),
+				Client:    c.RealIP(), // honours echo's IPExtractor
+				ReqBytes:  adapters.RequestSize(req, body),
+				RespBytes: res.Size,
+				LongLived: c.IsWebSocket() || model.IsEventStream(res.Header()),
+			})
+
+			// The error was handled above
+			return nil
+		}
+	}
+}
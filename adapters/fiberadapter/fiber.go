@@ -0,0 +1,75 @@
+// Package fiberadapter records requests served by gofiber/fiber.
+package fiberadapter
+
+import (
+	"strings"
+	"time"
+
+	"github.com/aurieli333/goapimon/adapters"
+	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/monitor"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware — Fiber middleware labelling requests with the route path, e.g. /users/:id
+//
+//	app := fiber.New()
+//	app.Use(fiberadapter.Middleware(goapimon.Monitor))
+func Middleware(m *monitor.Monitor) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !m.Monitored(c.Method(), c.Path()) {
+			return c.Next()
+		}
+
+		// Fiber strings point into reused buffers, clone what the store keeps
+		method := strings.Clone(c.Method())
+		traceID := strings.Clone(adapters.TraceIDFrom(c.UserContext(), c.Get("traceparent")))
+		websocket := strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket")
+		self := c.Route()
+
+		start := time.Now()
+		err := c.Next()
+		if err != nil {
+			// Let Fiber render the error now, so the final status is recorded
+			if herr := c.App().ErrorHandler(c, err); herr != nil {
+				_ = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+		elapsed := time.Since(start)
+
+		// The route stays on this middleware when nothing matched
+		path := model.UnmatchedRoute
+		if route := c.Route(); route != self && route.Path != "" {
+			path = route.Path
+		}
+
+		// Body() would read a body stream (SendStream, SendFile, SetBodyStreamWriter,
+		// StreamRequestBody) into memory and close it, streams count their Content-Length
+		req, res := c.Request(), c.Response()
+		reqBytes := max(int64(req.Header.ContentLength()), 0)
+		if !req.IsBodyStream() {
+			reqBytes = int64(len(req.Body()))
+		}
+		respBytes := max(int64(res.Header.ContentLength()), 0)
+		if !res.IsBodyStream() {
+			respBytes = int64(len(res.Body()))
+		}
+
+		m.Record(model.RequestRecord{
+			Timestamp: start,
+			Duration:  elapsed,
+			Status:    res.StatusCode(),
+			Method:    method,
+			Path:      path,
+			TraceID:   traceID,
+			Client:    strings.Clone(c.IP()), // honours fiber's ProxyHeader
+			ReqBytes:  reqBytes,
+			RespBytes: respBytes,
+			LongLived: websocket || model.IsEventStreamType(string(res.Header.ContentType())),
+		})
+
+		// The error was handled above
+		return nil
+	}
+}
@@ -1,11 +1,10 @@
 package adapters
 
 import (
-	"strings"
 	"time"
 
+	"github.com/aurieli333/goapimon/model"
 	"github.com/aurieli333/goapimon/monitor"
-	"github.com/aurieli333/goapimon/utility"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,18 +12,33 @@ import (
 // MiddlewareGin — adapter for Gin
 func MiddlewareGin(m *monitor.Monitor) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if utility.IsInternalPath(strings.Split(c.Request.URL.Path, `/`)[1]) || utility.IsInternalPath(c.Request.URL.Path) {
+		if !m.Monitored(c.Request.Method, c.Request.URL.Path) {
 			c.Next()
 			return
 		}
 
+		body := CountBody(c.Request)
 		start := time.Now()
 		c.Next()
 		elapsed := time.Since(start)
 
-		method := c.Request.Method
-		path := utility.NormalizePath(c.FullPath()) // FullPath for routes with params
-		status := c.Writer.Status()
-		m.CoreMiddleware(method, path, status, start, elapsed)
+		// FullPath is the route template, empty when no route matched
+		path := c.FullPath()
+		if path == "" {
+			path = model.UnmatchedRoute
+		}
+
+		m.Record(model.RequestRecord{
+			Timestamp: start,
+			Duration:  elapsed,
+			Status:    c.Writer.Status(),
+			Method:    c.Request.Method,
+			Path:      path,
+			TraceID:   TraceID(c.Request),
+			Client:    c.ClientIP(), // honours gin's trusted proxies
+			ReqBytes:  RequestSize(c.Request, body),
+			RespBytes: int64(max(c.Writer.Size(), 0)),
+			LongLived: c.IsWebsocket() || model.IsEventStream(c.Writer.Header()),
+		})
 	}
 }
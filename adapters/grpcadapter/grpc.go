@@ -0,0 +1,188 @@
+// Package grpcadapter records RPCs served by google.golang.org/grpc.
+//
+// Calls are stored under the GRPC method with the full method name as path,
+// e.g. GRPC /helloworld.Greeter/SayHello, next to the HTTP routes of the same monitor.
+// Filters see the same method and path, Prefix("/grpc.health.v1.Health/") excludes health checks.
+package grpcadapter
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/aurieli333/goapimon/adapters"
+	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/monitor"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Method — method label of recorded RPCs
+const Method = "GRPC"
+
+// UnaryServerInterceptor — records every unary call
+//
+//	srv := grpc.NewServer(
+//		grpc.UnaryInterceptor(grpcadapter.UnaryServerInterceptor(goapimon.Monitor)),
+//		grpc.StreamInterceptor(grpcadapter.StreamServerInterceptor(goapimon.Monitor)),
+//	)
+func UnaryServerInterceptor(m *monitor.Monitor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !m.Monitored(Method, info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		elapsed := time.Since(start)
+
+		code := status.Code(err)
+		respBytes := int64(0)
+		if err == nil {
+			respBytes = messageSize(resp)
+		}
+		m.Record(model.RequestRecord{
+			Timestamp: start,
+			Duration:  elapsed,
+			Status:    HTTPStatus(code),
+			Method:    Method,
+			Path:      info.FullMethod,
+			TraceID:   traceID(ctx),
+			Client:    clientIP(ctx),
+			ReqBytes:  messageSize(req),
+			RespBytes: respBytes,
+			GRPCCode:  code.String(),
+		})
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor — records every streaming call with its message counts.
+// Server and bidirectional streams live as long as the client keeps them open,
+// they are counted without latency like SSE responses.
+func StreamServerInterceptor(m *monitor.Monitor) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !m.Monitored(Method, info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		stream := &serverStream{ServerStream: ss}
+		start := time.Now()
+		err := handler(srv, stream)
+		elapsed := time.Since(start)
+
+		code := status.Code(err)
+		m.Record(model.RequestRecord{
+			Timestamp:    start,
+			Duration:     elapsed,
+			Status:       HTTPStatus(code),
+			Method:       Method,
+			Path:         info.FullMethod,
+			TraceID:      traceID(ss.Context()),
+			Client:       clientIP(ss.Context()),
+			ReqBytes:     stream.recvBytes.Load(),
+			RespBytes:    stream.sentBytes.Load(),
+			LongLived:    info.IsServerStream,
+			GRPCCode:     code.String(),
+			MsgsReceived: stream.received.Load(),
+			MsgsSent:     stream.sent.Load(),
+		})
+		return err
+	}
+}
+
+// serverStream counts messages and their encoded size.
+// Send and receive may run on different goroutines, hence the atomics.
+type serverStream struct {
+	grpc.ServerStream
+
+	received, sent       atomic.Int64
+	recvBytes, sentBytes atomic.Int64
+}
+
+func (s *serverStream) SendMsg(msg any) error {
+	err := s.ServerStream.SendMsg(msg)
+	if err == nil {
+		s.sent.Add(1)
+		s.sentBytes.Add(messageSize(msg))
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(msg any) error {
+	err := s.ServerStream.RecvMsg(msg)
+	if err == nil {
+		s.received.Add(1)
+		s.recvBytes.Add(messageSize(msg))
+	}
+	return err
+}
+
+// messageSize is the wire size of protobuf messages, 0 for other codecs
+func messageSize(msg any) int64 {
+	if pm, ok := msg.(proto.Message); ok {
+		return int64(proto.Size(pm))
+	}
+	return 0
+}
+
+func traceID(ctx context.Context) string {
+	var traceparent string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("traceparent"); len(v) > 0 {
+			traceparent = v[0]
+		}
+	}
+	return adapters.TraceIDFrom(ctx, traceparent)
+}
+
+// clientIP — address of the peer, empty when unknown
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// HTTPStatus — HTTP status equivalent of a gRPC code, as used by grpc-gateway.
+// Codes other than OK map to 4xx/5xx, so they count as errors.
+func HTTPStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // client closed request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default: // Unknown, Internal, DataLoss
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,175 @@
+package grpcadapter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/aurieli333/goapimon"
+	"github.com/aurieli333/goapimon/filter"
+	"github.com/aurieli333/goapimon/model"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testService answers UnaryCall with the requested status and echoes FullDuplexCall messages.
+type testService struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (testService) UnaryCall(ctx context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	if s := req.GetResponseStatus(); s != nil {
+		return nil, status.Error(codes.Code(s.GetCode()), s.GetMessage())
+	}
+	return &testpb.SimpleResponse{Payload: req.GetPayload()}, nil
+}
+
+func (testService) FullDuplexCall(stream grpc.BidiStreamingServer[testpb.StreamingOutputCallRequest, testpb.StreamingOutputCallResponse]) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{Payload: req.GetPayload()}); err != nil {
+			return err
+		}
+	}
+}
+
+// serve starts an in-process server behind both interceptors, health checks are excluded.
+func serve(t *testing.T) (*goapimon.Instance, *grpc.ClientConn) {
+	t.Helper()
+	mon, err := goapimon.New(goapimon.Options{
+		Exclude: []filter.Rule{filter.Prefix("/grpc.health.v1.Health/")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(mon.Monitor)),
+		grpc.StreamInterceptor(StreamServerInterceptor(mon.Monitor)),
+	)
+	testpb.RegisterTestServiceServer(srv, testService{})
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return mon, conn
+}
+
+// route returns the recorded stats of one RPC, nil when nothing was recorded.
+// The interceptors record before the response is sent, so a finished call is always visible.
+func route(mon *goapimon.Instance, fullMethod string) *model.RouteStats {
+	return mon.Store.Snapshot()[Method][fullMethod]
+}
+
+func TestUnary(t *testing.T) {
+	mon, conn := serve(t)
+	client := testpb.NewTestServiceClient(conn)
+	ctx := context.Background()
+
+	req := &testpb.SimpleRequest{Payload: &testpb.Payload{Body: []byte("hello")}}
+	if _, err := client.UnaryCall(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.UnaryCall(ctx, &testpb.SimpleRequest{
+		ResponseStatus: &testpb.EchoStatus{Code: int32(codes.NotFound), Message: "no such user"},
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("got %v, want NotFound", err)
+	}
+
+	s := route(mon, testpb.TestService_UnaryCall_FullMethodName)
+	if s == nil {
+		t.Fatalf("%s not recorded", testpb.TestService_UnaryCall_FullMethodName)
+	}
+	if s.TotalCount != 2 || s.TotalErrorCount != 1 {
+		t.Errorf("count %d, errors %d, want 2 and 1", s.TotalCount, s.TotalErrorCount)
+	}
+	if s.TotalGRPCStatus["OK"] != 1 || s.TotalGRPCStatus["NotFound"] != 1 {
+		t.Errorf("gRPC codes %v, want OK and NotFound once each", s.TotalGRPCStatus)
+	}
+	if s.TotalStatus[200] != 1 || s.TotalStatus[404] != 1 {
+		t.Errorf("HTTP equivalents %v, want 200 and 404 once each", s.TotalStatus)
+	}
+	if s.TotalReqBytes == 0 || s.TotalRespBytes == 0 {
+		t.Errorf("request bytes %d, response bytes %d, want both > 0", s.TotalReqBytes, s.TotalRespBytes)
+	}
+	if s.TotalLongLived != 0 {
+		t.Errorf("unary calls counted as long-lived: %d", s.TotalLongLived)
+	}
+}
+
+func TestBidiStream(t *testing.T) {
+	mon, conn := serve(t)
+	stream, err := testpb.NewTestServiceClient(conn).FullDuplexCall(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"a", "bb", "ccc"} {
+		if err := stream.Send(&testpb.StreamingOutputCallRequest{Payload: &testpb.Payload{Body: []byte(msg)}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := stream.Recv(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); !errors.Is(err, io.EOF) {
+		t.Fatalf("got %v, want io.EOF", err)
+	}
+
+	s := route(mon, testpb.TestService_FullDuplexCall_FullMethodName)
+	if s == nil {
+		t.Fatalf("%s not recorded", testpb.TestService_FullDuplexCall_FullMethodName)
+	}
+	if s.TotalMsgsReceived != 3 || s.TotalMsgsSent != 3 {
+		t.Errorf("messages received %d, sent %d, want 3 and 3", s.TotalMsgsReceived, s.TotalMsgsSent)
+	}
+	if s.TotalLongLived != 1 {
+		t.Errorf("long-lived %d, want 1: bidi streams are kept out of latency stats", s.TotalLongLived)
+	}
+	if s.TotalGRPCStatus["OK"] != 1 {
+		t.Errorf("gRPC codes %v, want OK once", s.TotalGRPCStatus)
+	}
+}
+
+func TestHealthCheckExcluded(t *testing.T) {
+	mon, conn := serve(t)
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("health status %v", resp.GetStatus())
+	}
+	if s := route(mon, healthpb.Health_Check_FullMethodName); s != nil {
+		t.Errorf("health check recorded %d times despite the filter", s.TotalCount)
+	}
+}
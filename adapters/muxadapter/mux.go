@@ -0,0 +1,35 @@
+// Package muxadapter records requests served by a gorilla/mux router.
+package muxadapter
+
+import (
+	"net/http"
+
+	"github.com/aurieli333/goapimon/adapters"
+	"github.com/aurieli333/goapimon/monitor"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware — gorilla/mux middleware labelling requests with the route path template, e.g. /users/{id}.
+// Router middlewares only run for matched routes, wrap the router itself
+// with adapters.MiddlewareNetHTTP to also see 404s.
+//
+//	r := mux.NewRouter()
+//	r.Use(muxadapter.Middleware(goapimon.Monitor))
+func Middleware(m *monitor.Monitor) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return adapters.MiddlewareHTTP(m, next, pathTemplate)
+	}
+}
+
+func pathTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return tpl
+}
@@ -2,29 +2,63 @@ package adapters
 
 import (
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/aurieli333/goapimon/model"
 	"github.com/aurieli333/goapimon/monitor"
-	"github.com/aurieli333/goapimon/utility"
 )
 
-// MiddlewareNetHTTP — adapter for net/http
+// RouteFunc — returns the route template matched by a router, or "" when unknown
+type RouteFunc func(r *http.Request) string
+
+// MiddlewareNetHTTP — adapter for net/http. Requests matched by an http.ServeMux
+// (or adapters.ServeMux on Go 1.22) are labelled with the mux pattern, e.g. /users/{id},
+// everything else with the normalized URL path.
 func MiddlewareNetHTTP(m *monitor.Monitor, next http.Handler) http.Handler {
+	return MiddlewareHTTP(m, next, ServeMuxPattern)
+}
+
+// MiddlewareHTTP — net/http middleware labelling requests with route(r),
+// falling back to the normalized URL path. 404 and 405 responses without a route
+// are grouped under model.UnmatchedRoute, so scanners don't create a route per URL.
+// Used by the router adapters.
+func MiddlewareHTTP(m *monitor.Monitor, next http.Handler, route RouteFunc) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if utility.IsInternalPath(strings.Split(r.URL.Path, `/`)[1]) || utility.IsInternalPath(r.URL.Path) {
+		if !m.Monitored(r.Method, r.URL.Path) {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		sr := &model.StatusRecorder{ResponseWriter: w, Status: 200}
+		r = trackPattern(r)
+		sr := model.NewStatusRecorder(w)
+		body := CountBody(r)
 		start := time.Now()
-		next.ServeHTTP(sr, r)
+		next.ServeHTTP(sr.Writer(), r)
 		elapsed := time.Since(start)
 
-		method := r.Method
-		path := utility.NormalizePath(r.URL.Path)
-		m.CoreMiddleware(method, path, sr.Status, start, elapsed)
+		path := ""
+		if route != nil {
+			path = route(r)
+		}
+		switch {
+		case path != "":
+		case route != nil && (sr.Status == http.StatusNotFound || sr.Status == http.StatusMethodNotAllowed):
+			path = model.UnmatchedRoute
+		default:
+			path = m.NormalizePath(r.URL.Path)
+		}
+
+		m.Record(model.RequestRecord{
+			Timestamp: start,
+			Duration:  elapsed,
+			Status:    sr.Status,
+			Method:    r.Method,
+			Path:      path,
+			TraceID:   TraceID(r),
+			Client:    ClientIP(r),
+			ReqBytes:  RequestSize(r, body),
+			RespBytes: sr.Bytes,
+			LongLived: sr.LongLived(),
+		})
 	})
 }
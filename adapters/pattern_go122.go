@@ -0,0 +1,33 @@
+//go:build !go1.23
+
+package adapters
+
+import (
+	"context"
+	"net/http"
+)
+
+// Before Go 1.23 the matched pattern is not exported, handlers registered
+// through ServeMux report it into a slot carried by the request context.
+type patternSlot struct {
+	pattern string
+}
+
+type patternKey struct{}
+
+func trackPattern(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), patternKey{}, &patternSlot{}))
+}
+
+func setPattern(r *http.Request, pattern string) {
+	if slot, ok := r.Context().Value(patternKey{}).(*patternSlot); ok {
+		slot.pattern = pattern
+	}
+}
+
+func matchedPattern(r *http.Request) string {
+	if slot, ok := r.Context().Value(patternKey{}).(*patternSlot); ok {
+		return slot.pattern
+	}
+	return ""
+}
@@ -0,0 +1,16 @@
+//go:build go1.23
+
+package adapters
+
+import "net/http"
+
+// trackPattern is a no-op, http.ServeMux stores the pattern in r.Pattern since Go 1.23
+func trackPattern(r *http.Request) *http.Request {
+	return r
+}
+
+func setPattern(r *http.Request, pattern string) {}
+
+func matchedPattern(r *http.Request) string {
+	return r.Pattern
+}
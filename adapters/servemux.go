@@ -0,0 +1,37 @@
+package adapters
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ServeMux — http.ServeMux that also reports matched patterns on Go 1.22.
+// Since Go 1.23 a plain http.ServeMux works the same way.
+type ServeMux struct {
+	*http.ServeMux
+}
+
+func NewServeMux() *ServeMux {
+	return &ServeMux{ServeMux: http.NewServeMux()}
+}
+
+func (mux *ServeMux) Handle(pattern string, handler http.Handler) {
+	mux.ServeMux.Handle(pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setPattern(r, pattern)
+		handler.ServeHTTP(w, r)
+	}))
+}
+
+func (mux *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	mux.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// ServeMuxPattern — RouteFunc returning the http.ServeMux pattern that matched r,
+// without its method part: "GET /users/{id}" is reported as "/users/{id}"
+func ServeMuxPattern(r *http.Request) string {
+	pattern := matchedPattern(r)
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " \t")
+	}
+	return pattern
+}
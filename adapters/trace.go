@@ -0,0 +1,42 @@
+package adapters
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type traceIDKey struct{}
+
+// WithTraceID — returns a context whose trace ID is attached to recorded requests
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, traceID)
+}
+
+// TraceID — trace ID of a request, taken from the context or the W3C traceparent header
+func TraceID(r *http.Request) string {
+	return TraceIDFrom(r.Context(), r.Header.Get("traceparent"))
+}
+
+// TraceIDFrom — same as TraceID for frameworks without *http.Request
+func TraceIDFrom(ctx context.Context, traceparent string) string {
+	if ctx != nil {
+		if id, ok := ctx.Value(traceIDKey{}).(string); ok && id != "" {
+			return id
+		}
+	}
+	return parseTraceparent(traceparent)
+}
+
+// parseTraceparent extracts trace-id from "version-traceid-parentid-flags"
+func parseTraceparent(h string) string {
+	parts := strings.Split(strings.TrimSpace(h), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 {
+		return ""
+	}
+	id := strings.ToLower(parts[1])
+	if strings.Trim(id, "0") == "" || strings.Trim(id, "0123456789abcdef") != "" {
+		return ""
+	}
+	return id
+}
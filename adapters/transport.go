@@ -0,0 +1,106 @@
+package adapters
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/monitor"
+)
+
+// Kinds of outbound failures that produced no response
+const (
+	TransportErrorDNS               = "dns"
+	TransportErrorTimeout           = "timeout"
+	TransportErrorCanceled          = "canceled"
+	TransportErrorConnectionRefused = "connection_refused"
+	TransportErrorConnectionReset   = "connection_reset"
+	TransportErrorTLS               = "tls"
+	TransportErrorOther             = "other"
+)
+
+// RoundTripper — http.RoundTripper recording outbound calls per host + normalized path.
+// Latency is measured until response headers arrive, the body is read by the caller.
+// Exclude/Include rules are matched against host + raw path, e.g. filter.Prefix("metadata.internal/").
+// A nil next uses http.DefaultTransport.
+//
+//	client := &http.Client{Transport: adapters.RoundTripper(goapimon.Monitor, nil)}
+func RoundTripper(m *monitor.Monitor, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &roundTripper{monitor: m, next: next}
+}
+
+type roundTripper struct {
+	monitor *monitor.Monitor
+	next    http.RoundTripper
+}
+
+func (t *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if host == "" {
+		host = req.Host
+	}
+	if !t.monitor.Monitored(req.Method, model.OutboundPath(host, req.URL.Path)) {
+		return t.next.RoundTrip(req)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	rec := model.RequestRecord{
+		Timestamp: start,
+		Duration:  elapsed,
+		Method:    req.Method,
+		Path:      model.OutboundPath(host, t.monitor.NormalizePath(req.URL.Path)),
+		TraceID:   TraceID(req),
+		ReqBytes:  max(req.ContentLength, 0),
+		Outbound:  true,
+	}
+	if err != nil {
+		rec.TransportError = TransportErrorKind(err)
+	} else {
+		rec.Status = resp.StatusCode
+		rec.RespBytes = max(resp.ContentLength, 0)
+		rec.LongLived = model.IsEventStream(resp.Header)
+	}
+	t.monitor.Record(rec)
+	return resp, err
+}
+
+// TransportErrorKind — classifies an error returned by a RoundTripper
+func TransportErrorKind(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return TransportErrorCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return TransportErrorTimeout
+	case errors.As(err, &dnsErr):
+		return TransportErrorDNS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return TransportErrorTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return TransportErrorConnectionRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return TransportErrorConnectionReset
+	case errors.As(err, &certErr), errors.As(err, &recordErr),
+		errors.As(err, &authorityErr), errors.As(err, &hostnameErr):
+		return TransportErrorTLS
+	default:
+		return TransportErrorOther
+	}
+}
@@ -0,0 +1,224 @@
+// Package auth restricts access to the dashboard, its JSON and CSV exports
+// and the Prometheus endpoint.
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Config — access rules. Every configured check must pass: the client address is in AllowIPs,
+// the request carries valid basic auth or bearer credentials, and Authorize returns true.
+// Checks left empty are skipped, so the zero Config allows everything.
+type Config struct {
+	// BasicAuth — user name to bcrypt hash of the password,
+	// e.g. from `htpasswd -nbB user password` or bcrypt.GenerateFromPassword.
+	BasicAuth map[string]string
+
+	// BearerTokens — accepted "Authorization: Bearer <token>" values, e.g. for Prometheus scrapes.
+	// Either a bearer token or basic auth credentials are enough when both are configured.
+	BearerTokens []string
+
+	// AllowIPs — client addresses or CIDR ranges, e.g. "10.0.0.0/8" or "::1".
+	// The connection's remote address is used, forwarded headers are not trusted.
+	AllowIPs []string
+
+	// Authorize — custom check, e.g. a session lookup of an SSO proxy.
+	Authorize func(*http.Request) bool
+
+	// Realm — basic auth realm shown by browsers, defaults to "goapimon".
+	Realm string
+}
+
+// maxCached — successful basic auth checks remembered, so polling pages don't pay for bcrypt
+const maxCached = 64
+
+// Guard — compiled Config, a nil Guard allows every request
+type Guard struct {
+	users     map[string][]byte
+	tokens    [][]byte
+	prefixes  []netip.Prefix
+	authorize func(*http.Request) bool
+	challenge string
+
+	// dummy is compared when the user is unknown, so timing doesn't reveal user names
+	dummy []byte
+
+	mu     sync.Mutex
+	cached map[[sha256.Size]byte]bool
+}
+
+// New validates c and returns its Guard, nil when c configures no check.
+func New(c Config) (*Guard, error) {
+	if len(c.BasicAuth) == 0 && len(c.BearerTokens) == 0 && len(c.AllowIPs) == 0 && c.Authorize == nil {
+		return nil, nil
+	}
+
+	g := &Guard{
+		users:     make(map[string][]byte, len(c.BasicAuth)),
+		authorize: c.Authorize,
+		cached:    make(map[[sha256.Size]byte]bool),
+	}
+	for user, hash := range c.BasicAuth {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("auth: password hash of user %q: %w", user, err)
+		}
+		g.users[user] = []byte(hash)
+	}
+	if len(g.users) > 0 {
+		dummy, err := bcrypt.GenerateFromPassword([]byte("goapimon"), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		g.dummy = dummy
+	}
+	for _, token := range c.BearerTokens {
+		if token == "" {
+			return nil, errors.New("auth: empty bearer token")
+		}
+		g.tokens = append(g.tokens, []byte(token))
+	}
+	for _, s := range c.AllowIPs {
+		prefix, err := parsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("auth: allowed IP %q: %w", s, err)
+		}
+		g.prefixes = append(g.prefixes, prefix)
+	}
+
+	realm := c.Realm
+	if realm == "" {
+		realm = "goapimon"
+	}
+	var schemes []string
+	if len(g.users) > 0 {
+		schemes = append(schemes, "Basic realm="+strconv.Quote(realm)+`, charset="UTF-8"`)
+	}
+	if len(g.tokens) > 0 {
+		schemes = append(schemes, "Bearer realm="+strconv.Quote(realm))
+	}
+	g.challenge = strings.Join(schemes, ", ")
+	return g, nil
+}
+
+// parsePrefix reads a CIDR range or a single address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Check reports whether r may proceed. Otherwise it has answered
+// 401 with a challenge for missing or wrong credentials, or 403.
+func (g *Guard) Check(w http.ResponseWriter, r *http.Request) bool {
+	if g == nil {
+		return true
+	}
+	if len(g.prefixes) > 0 && !g.allowedIP(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	if g.challenge != "" && !g.authenticated(r) {
+		w.Header().Set("WWW-Authenticate", g.challenge)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if g.authorize != nil && !g.authorize(r) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Handler — next behind the guard
+func (g *Guard) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.Check(w, r) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (g *Guard) allowedIP(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap().WithZone("")
+	for _, p := range g.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Guard) authenticated(r *http.Request) bool {
+	header := r.Header.Get("Authorization")
+	if token, ok := cutScheme(header, "Bearer"); ok && len(g.tokens) > 0 {
+		match := 0
+		for _, t := range g.tokens {
+			match |= subtle.ConstantTimeCompare([]byte(token), t)
+		}
+		return match == 1
+	}
+	if user, pass, ok := r.BasicAuth(); ok && len(g.users) > 0 {
+		return g.checkPassword(user, pass)
+	}
+	return false
+}
+
+// cutScheme returns the credentials of an Authorization header using scheme, case-insensitively.
+func cutScheme(header, scheme string) (string, bool) {
+	if len(header) <= len(scheme) || !strings.EqualFold(header[:len(scheme)], scheme) || header[len(scheme)] != ' ' {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(scheme)+1:]), true
+}
+
+func (g *Guard) checkPassword(user, pass string) bool {
+	key := sha256.Sum256([]byte(user + "\x00" + pass))
+	g.mu.Lock()
+	ok := g.cached[key]
+	g.mu.Unlock()
+	if ok {
+		return true
+	}
+
+	hash, known := g.users[user]
+	if !known {
+		hash = g.dummy
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(pass)) != nil || !known {
+		return false
+	}
+
+	g.mu.Lock()
+	if len(g.cached) >= maxCached {
+		clear(g.cached)
+	}
+	g.cached[key] = true
+	g.mu.Unlock()
+	return true
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func hash(t *testing.T, password string) string {
+	t.Helper()
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(h)
+}
+
+// request builds a request from remoteAddr with an optional Authorization header
+func request(remoteAddr, authorization string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/__goapimon/", nil)
+	r.RemoteAddr = remoteAddr
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return r
+}
+
+func basic(user, pass string) string {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth(user, pass)
+	return r.Header.Get("Authorization")
+}
+
+func TestCheck(t *testing.T) {
+	users := map[string]string{"alice": hash(t, "s3cret")}
+	tokens := []string{"scrape-token", "other-token"}
+
+	for _, tt := range []struct {
+		name          string
+		config        Config
+		remoteAddr    string
+		authorization string
+		want          int
+	}{
+		// Basic auth
+		{"basic right password", Config{BasicAuth: users}, "192.0.2.1:1234", basic("alice", "s3cret"), http.StatusOK},
+		{"basic wrong password", Config{BasicAuth: users}, "192.0.2.1:1234", basic("alice", "wrong"), http.StatusUnauthorized},
+		{"basic unknown user", Config{BasicAuth: users}, "192.0.2.1:1234", basic("bob", "s3cret"), http.StatusUnauthorized},
+		{"basic missing", Config{BasicAuth: users}, "192.0.2.1:1234", "", http.StatusUnauthorized},
+		{"basic sent a token", Config{BasicAuth: users}, "192.0.2.1:1234", "Bearer scrape-token", http.StatusUnauthorized},
+
+		// Bearer tokens
+		{"bearer match", Config{BearerTokens: tokens}, "192.0.2.1:1234", "Bearer scrape-token", http.StatusOK},
+		{"bearer second token", Config{BearerTokens: tokens}, "192.0.2.1:1234", "Bearer other-token", http.StatusOK},
+		{"bearer mismatch", Config{BearerTokens: tokens}, "192.0.2.1:1234", "Bearer scrape-tokeN", http.StatusUnauthorized},
+		{"bearer prefix of a token", Config{BearerTokens: tokens}, "192.0.2.1:1234", "Bearer scrape", http.StatusUnauthorized},
+		{"bearer scheme lower case", Config{BearerTokens: tokens}, "192.0.2.1:1234", "bearer scrape-token", http.StatusOK},
+		{"bearer scheme upper case", Config{BearerTokens: tokens}, "192.0.2.1:1234", "BEARER scrape-token", http.StatusOK},
+		{"bearer without space", Config{BearerTokens: tokens}, "192.0.2.1:1234", "Bearerscrape-token", http.StatusUnauthorized},
+		{"bearer sent basic", Config{BearerTokens: tokens}, "192.0.2.1:1234", basic("alice", "s3cret"), http.StatusUnauthorized},
+
+		// Both configured, either one is enough
+		{"both basic", Config{BasicAuth: users, BearerTokens: tokens}, "192.0.2.1:1234", basic("alice", "s3cret"), http.StatusOK},
+		{"both bearer", Config{BasicAuth: users, BearerTokens: tokens}, "192.0.2.1:1234", "Bearer scrape-token", http.StatusOK},
+		{"both wrong", Config{BasicAuth: users, BearerTokens: tokens}, "192.0.2.1:1234", "Bearer nope", http.StatusUnauthorized},
+
+		// IP allowlist
+		{"ip exact", Config{AllowIPs: []string{"192.0.2.1"}}, "192.0.2.1:1234", "", http.StatusOK},
+		{"ip other", Config{AllowIPs: []string{"192.0.2.1"}}, "192.0.2.2:1234", "", http.StatusForbidden},
+		{"ip in cidr", Config{AllowIPs: []string{"10.0.0.0/8"}}, "10.20.30.40:1234", "", http.StatusOK},
+		{"ip outside cidr", Config{AllowIPs: []string{"10.0.0.0/8"}}, "11.0.0.1:1234", "", http.StatusForbidden},
+		{"ip unmasked cidr", Config{AllowIPs: []string{"10.1.2.3/8"}}, "10.9.9.9:1234", "", http.StatusOK},
+		{"ipv6 loopback", Config{AllowIPs: []string{"::1"}}, "[::1]:1234", "", http.StatusOK},
+		{"ipv4-mapped client", Config{AllowIPs: []string{"10.0.0.0/8"}}, "[::ffff:10.1.2.3]:1234", "", http.StatusOK},
+		{"ipv4-mapped allowed", Config{AllowIPs: []string{"::ffff:127.0.0.1"}}, "127.0.0.1:1234", "", http.StatusOK},
+		{"zoned client", Config{AllowIPs: []string{"fe80::/10"}}, "[fe80::1%eth0]:1234", "", http.StatusOK},
+		{"zoned client outside", Config{AllowIPs: []string{"fd00::/8"}}, "[fe80::1%eth0]:1234", "", http.StatusForbidden},
+		{"address without port", Config{AllowIPs: []string{"192.0.2.1"}}, "192.0.2.1", "", http.StatusOK},
+		{"unparsable address", Config{AllowIPs: []string{"192.0.2.1"}}, "pipe", "", http.StatusForbidden},
+		{"ip checked before credentials", Config{AllowIPs: []string{"10.0.0.0/8"}, BearerTokens: tokens}, "192.0.2.1:1234", "Bearer scrape-token", http.StatusForbidden},
+		{"ip and credentials", Config{AllowIPs: []string{"10.0.0.0/8"}, BearerTokens: tokens}, "10.0.0.1:1234", "Bearer scrape-token", http.StatusOK},
+
+		// Authorize
+		{"authorize true", Config{Authorize: func(*http.Request) bool { return true }}, "192.0.2.1:1234", "", http.StatusOK},
+		{"authorize false", Config{Authorize: func(*http.Request) bool { return false }}, "192.0.2.1:1234", "", http.StatusForbidden},
+		{"authorize after credentials", Config{BearerTokens: tokens, Authorize: func(*http.Request) bool { return false }}, "192.0.2.1:1234", "Bearer scrape-token", http.StatusForbidden},
+		{"credentials before authorize", Config{BearerTokens: tokens, Authorize: func(*http.Request) bool { return true }}, "192.0.2.1:1234", "", http.StatusUnauthorized},
+
+		// No checks
+		{"zero config", Config{}, "192.0.2.1:1234", "", http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := New(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			ok := g.Check(w, request(tt.remoteAddr, tt.authorization))
+			if ok != (tt.want == http.StatusOK) {
+				t.Errorf("Check() = %v, want %d", ok, tt.want)
+			}
+			if !ok && w.Code != tt.want {
+				t.Errorf("status %d, want %d", w.Code, tt.want)
+			}
+			if challenge := w.Header().Get("WWW-Authenticate"); (w.Code == http.StatusUnauthorized) != (challenge != "") {
+				t.Errorf("status %d with challenge %q", w.Code, challenge)
+			}
+		})
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config Config
+		want   string
+	}{
+		{"basic", Config{BasicAuth: map[string]string{"alice": hash(t, "pw")}}, `Basic realm="goapimon", charset="UTF-8"`},
+		{"bearer", Config{BearerTokens: []string{"t"}, Realm: "metrics"}, `Bearer realm="metrics"`},
+		{"both", Config{BasicAuth: map[string]string{"alice": hash(t, "pw")}, BearerTokens: []string{"t"}, Realm: `ops "east"`},
+			`Basic realm="ops \"east\"", charset="UTF-8", Bearer realm="ops \"east\""`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := New(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			g.Check(w, request("192.0.2.1:1234", ""))
+			if got := w.Header().Get("WWW-Authenticate"); got != tt.want {
+				t.Errorf("WWW-Authenticate %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCachedPassword(t *testing.T) {
+	g, err := New(Config{BasicAuth: map[string]string{"alice": hash(t, "s3cret")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.checkPassword("alice", "s3cret") {
+		t.Fatal("right password rejected")
+	}
+
+	// A cached success is accepted without comparing the hash again
+	g.users["alice"] = []byte(hash(t, "changed"))
+	if !g.checkPassword("alice", "s3cret") {
+		t.Error("cached success not used")
+	}
+	// Failures are never cached
+	if g.checkPassword("alice", "wrong") || g.checkPassword("alice", "wrong") {
+		t.Error("wrong password accepted")
+	}
+	if len(g.cached) != 1 {
+		t.Errorf("%d cached entries, want 1", len(g.cached))
+	}
+}
+
+func TestNewRejectsBadConfig(t *testing.T) {
+	for name, c := range map[string]Config{
+		"plain password": {BasicAuth: map[string]string{"alice": "s3cret"}},
+		"empty token":    {BearerTokens: []string{""}},
+		"bad address":    {AllowIPs: []string{"10.0.0.300"}},
+		"bad cidr":       {AllowIPs: []string{"10.0.0.0/33"}},
+	} {
+		if _, err := New(c); err == nil {
+			t.Errorf("%s: no error", name)
+		} else if !strings.HasPrefix(err.Error(), "auth: ") {
+			t.Errorf("%s: error %q lacks the package prefix", name, err)
+		}
+	}
+}
+
+func TestNilGuard(t *testing.T) {
+	var g *Guard
+	if !g.Check(httptest.NewRecorder(), request("192.0.2.1:1234", "")) {
+		t.Error("nil Guard rejected a request")
+	}
+}
@@ -0,0 +1,334 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+)
+
+// apiPrefix — JSON API below the dashboard base path
+const apiPrefix = "api/v1/"
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// WindowInfo — window listed by /api/v1/windows, Seconds is 0 for total
+type WindowInfo struct {
+	Name    string `json:"name"`
+	Seconds int64  `json:"seconds"`
+}
+
+// RoutesPage — response of /api/v1/routes
+type RoutesPage struct {
+	Window string `json:"window"`
+	Scope  string `json:"scope"`
+	Total  int    `json:"total"` // matching routes before pagination
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+	Routes []Row  `json:"routes"`
+}
+
+// RouteDetail — response of /api/v1/routes/{method}/{path}, one row per window
+type RouteDetail struct {
+	Method  string         `json:"method"`
+	Path    string         `json:"path"`
+	Scope   string         `json:"scope"`
+	Windows map[string]Row `json:"windows"`
+}
+
+// sortKeys — values of the sort parameter, prefixed with - for descending order
+var sortKeys = map[string]func(Row) float64{
+	"count":      func(r Row) float64 { return float64(r.Count) },
+	"errors":     func(r Row) float64 { return float64(r.ErrorCount) },
+	"error_rate": func(r Row) float64 { return r.ErrorRate },
+	"avg":        func(r Row) float64 { return r.Avg },
+	"min":        func(r Row) float64 { return r.Min },
+	"max":        func(r Row) float64 { return r.Max },
+	"p50":        func(r Row) float64 { return r.P50 },
+	"p90":        func(r Row) float64 { return r.P90 },
+	"p95":        func(r Row) float64 { return r.P95 },
+	"p99":        func(r Row) float64 { return r.P99 },
+	"rps":        func(r Row) float64 { return r.Throughput },
+	"bytes":      func(r Row) float64 { return float64(r.Bytes) },
+	"bandwidth":  func(r Row) float64 { return r.Bandwidth },
+}
+
+// serveAPI answers requests below base + apiPrefix, rest is the remaining path.
+func (d *Dashboard) serveAPI(w http.ResponseWriter, r *http.Request, rest string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		apiError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	switch {
+	case rest == "stream":
+		d.stream(w, r)
+	case rest == "windows":
+		d.apiWindows(w)
+	case rest == "routes":
+		d.apiRoutes(w, r)
+	case strings.HasPrefix(rest, "routes/"):
+		d.apiRoute(w, r, strings.TrimPrefix(rest, "routes/"))
+	case strings.HasPrefix(rest, "history/"):
+		d.apiHistory(w, r, strings.TrimPrefix(rest, "history/"))
+	case strings.HasPrefix(rest, "requests/"):
+		d.apiRequests(w, r, strings.TrimPrefix(rest, "requests/"))
+	case strings.HasPrefix(rest, "latency/"):
+		d.apiLatency(w, r, strings.TrimPrefix(rest, "latency/"))
+	default:
+		apiError(w, http.StatusNotFound, "unknown endpoint")
+	}
+}
+
+func (d *Dashboard) apiWindows(w http.ResponseWriter) {
+	windows := make([]WindowInfo, 0, len(d.Windows)+1)
+	for _, win := range d.Windows {
+		windows = append(windows, WindowInfo{Name: win.Name, Seconds: int64(win.Length / time.Second)})
+	}
+	windows = append(windows, WindowInfo{Name: "total"})
+	writeJSON(w, http.StatusOK, windows)
+}
+
+// apiRoutes lists routes of one window.
+// Query: window (default: first window), scope (inbound|outbound), method, path (substring),
+// status (class such as 5xx or exact code), sort (e.g. -p95), limit, offset.
+func (d *Dashboard) apiRoutes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	stats, scope, ok := d.scopeStats(w, q.Get("scope"))
+	if !ok {
+		return
+	}
+
+	window := q.Get("window")
+	if window == "" {
+		window = "total"
+		if len(d.Windows) > 0 {
+			window = d.Windows[0].Name
+		}
+	}
+	var rows []Row
+	now := time.Now()
+	if window == "total" {
+		rows = calcTotal(stats, now)
+	} else {
+		win, found := d.window(window)
+		if !found {
+			apiError(w, http.StatusBadRequest, "unknown window "+strconv.Quote(window))
+			return
+		}
+		rows = calcWindow(stats, win, now)
+	}
+
+	rows, err := filterRows(rows, q.Get("method"), q.Get("path"), q.Get("status"))
+	if err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := sortRows(rows, q.Get("sort")); err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, err := intParam(q.Get("limit"), defaultPageSize)
+	if err != nil || limit < 1 || limit > maxPageSize {
+		apiError(w, http.StatusBadRequest, "limit must be between 1 and "+strconv.Itoa(maxPageSize))
+		return
+	}
+	offset, err := intParam(q.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		apiError(w, http.StatusBadRequest, "offset must not be negative")
+		return
+	}
+
+	page := RoutesPage{Window: window, Scope: scope, Total: len(rows), Offset: offset, Limit: limit, Routes: []Row{}}
+	if offset < len(rows) {
+		page.Routes = rows[offset:min(offset+limit, len(rows))]
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// apiRoute reports one route in every window, rest is "{method}/{path}".
+func (d *Dashboard) apiRoute(w http.ResponseWriter, r *http.Request, rest string) {
+	stats, scope, ok := d.scopeStats(w, r.URL.Query().Get("scope"))
+	if !ok {
+		return
+	}
+
+	var s *model.RouteStats
+	method, path, found := routeParam(rest, func(method, path string) bool {
+		s = stats[method][path]
+		return s != nil
+	})
+	if !found {
+		apiError(w, http.StatusNotFound, "route not found")
+		return
+	}
+
+	single := map[string]map[string]*model.RouteStats{method: {path: s}}
+	detail := RouteDetail{Method: method, Path: path, Scope: scope, Windows: make(map[string]Row)}
+	for window, rows := range d.calcData(single) {
+		if len(rows) > 0 {
+			detail.Windows[window] = rows[0]
+		}
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// scopeStats snapshots served routes or outbound calls.
+func (d *Dashboard) scopeStats(w http.ResponseWriter, scope string) (map[string]map[string]*model.RouteStats, string, bool) {
+	outbound, scope, ok := parseScope(w, scope)
+	switch {
+	case !ok:
+		return nil, "", false
+	case outbound:
+		return d.Store.OutboundSnapshot(), scope, true
+	default:
+		return d.Store.Snapshot(), scope, true
+	}
+}
+
+// parseScope reads the scope parameter, answering 400 when it is unknown.
+func parseScope(w http.ResponseWriter, scope string) (outbound bool, name string, ok bool) {
+	switch scope {
+	case "", "inbound":
+		return false, "inbound", true
+	case "outbound":
+		return true, "outbound", true
+	default:
+		apiError(w, http.StatusBadRequest, "scope must be inbound or outbound")
+		return false, "", false
+	}
+}
+
+// routeParam splits "{method}/{path}" of the per-route endpoints.
+// Paths usually start with /, grouping routes and outbound hosts don't,
+// so found is asked for "/"+path first.
+func routeParam(rest string, found func(method, path string) bool) (method, path string, ok bool) {
+	method, path, _ = strings.Cut(rest, "/")
+	if found(method, "/"+path) {
+		return method, "/" + path, true
+	}
+	return method, path, found(method, path)
+}
+
+func (d *Dashboard) window(name string) (model.Window, bool) {
+	for _, win := range d.Windows {
+		if win.Name == name {
+			return win, true
+		}
+	}
+	return model.Window{}, false
+}
+
+// filterRows keeps rows of method, containing path and with at least one response of status,
+// given as a code (404) or a class (4xx).
+func filterRows(rows []Row, method, path, status string) ([]Row, error) {
+	var match func(code int) bool
+	if status != "" {
+		if len(status) == 3 && strings.HasSuffix(status, "xx") && status[0] >= '1' && status[0] <= '5' {
+			class := int(status[0]-'0') * 100
+			match = func(code int) bool { return code >= class && code < class+100 }
+		} else if code, err := strconv.Atoi(status); err == nil {
+			match = func(c int) bool { return c == code }
+		} else {
+			return nil, errors.New("status must be a code such as 404 or a class such as 5xx")
+		}
+	}
+
+	path = strings.ToLower(path)
+	out := rows[:0]
+	for _, row := range rows {
+		if method != "" && !strings.EqualFold(row.Method, method) {
+			continue
+		}
+		if path != "" && !strings.Contains(strings.ToLower(row.Path), path) {
+			continue
+		}
+		if match != nil && !hasStatus(row.Status, match) {
+			continue
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+func hasStatus(status map[int]int, match func(int) bool) bool {
+	for code, cnt := range status {
+		if cnt > 0 && match(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortRows orders rows by key, method and path by default.
+func sortRows(rows []Row, key string) error {
+	desc := strings.HasPrefix(key, "-")
+	key = strings.TrimPrefix(key, "-")
+
+	byRoute := func(a, b Row) bool {
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Path < b.Path
+	}
+	var less func(a, b Row) bool
+	switch key {
+	case "", "method":
+		less = byRoute
+	case "path":
+		less = func(a, b Row) bool {
+			if a.Path != b.Path {
+				return a.Path < b.Path
+			}
+			return a.Method < b.Method
+		}
+	default:
+		value, ok := sortKeys[key]
+		if !ok {
+			return errors.New("unknown sort key " + strconv.Quote(key))
+		}
+		less = func(a, b Row) bool {
+			if va, vb := value(a), value(b); va != vb {
+				return va < vb
+			}
+			return byRoute(a, b)
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if desc {
+			return less(rows[j], rows[i])
+		}
+		return less(rows[i], rows[j])
+	})
+	return nil
+}
+
+func intParam(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func apiError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
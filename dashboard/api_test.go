@@ -0,0 +1,285 @@
+package dashboard
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/store"
+)
+
+const base = "/__goapimon/"
+
+// testDashboard serves a store seeded with a few seconds old requests, inside the 1m window.
+func testDashboard(history bool) *Dashboard {
+	opts := store.Options{
+		Retention:        time.Minute,
+		BucketWidth:      time.Second,
+		HistogramBuckets: []float64{.01, .1, 1},
+		Samples:          5,
+	}
+	if history {
+		opts.HistoryRetention = time.Minute
+		opts.HistoryInterval = 10 * time.Second
+	}
+	s := store.NewStore(opts)
+
+	// Requests are bucketed by completion, all of them must end within one second
+	at := time.Now().Add(-5 * time.Second).Truncate(time.Second)
+	for _, rec := range []model.RequestRecord{
+		{Duration: 8 * time.Millisecond, Status: 200, Method: "GET", Path: "/users/:id"},
+		{Duration: 20 * time.Millisecond, Status: 200, Method: "GET", Path: "/users/:id"},
+		{Duration: 300 * time.Millisecond, Status: 500, Method: "GET", Path: "/users/:id", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{Duration: 50 * time.Millisecond, Status: 201, Method: "POST", Path: "/users"},
+		{Duration: 5 * time.Millisecond, Status: 404, Method: "POST", Path: "/users"},
+		{Duration: time.Millisecond, Status: 200, Method: "GET", Path: "/health"},
+		{Duration: 2 * time.Millisecond, Status: 404, Method: "GET", Path: model.UnmatchedRoute},
+		{Duration: 40 * time.Millisecond, Status: 200, Method: "GET", Path: model.OutboundPath("api.example.com", "/v1/items"), Outbound: true},
+	} {
+		rec.Timestamp = at
+		s.Record(rec)
+	}
+
+	d := NewDashboard(s, []model.Window{{Name: "1m", Length: time.Minute}})
+	d.Enable()
+	return d
+}
+
+// get requests base + target and decodes a 200 answer into v.
+func get(t *testing.T, d *Dashboard, target string, v any) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	d.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, base+target, nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s: Content-Type %q", target, ct)
+	}
+	if w.Code == http.StatusOK && v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s: %v", target, err)
+		}
+	}
+	return w.Code
+}
+
+func routeNames(rows []Row) []string {
+	names := make([]string, 0, len(rows))
+	for _, r := range rows {
+		names = append(names, r.Method+" "+r.Path)
+	}
+	return names
+}
+
+func TestAPIRoutes(t *testing.T) {
+	d := testDashboard(false)
+	all := []string{"GET /health", "GET /users/:id", "GET __unmatched__", "POST /users"}
+
+	for _, tt := range []struct {
+		name   string
+		query  url.Values
+		window string
+		scope  string
+		total  int
+		routes []string
+	}{
+		{"defaults", nil, "1m", "inbound", 4, all},
+		{"total window", url.Values{"window": {"total"}}, "total", "inbound", 4, all},
+		{"status class", url.Values{"status": {"5xx"}}, "1m", "inbound", 1, []string{"GET /users/:id"}},
+		{"status code", url.Values{"status": {"404"}}, "1m", "inbound", 2, []string{"GET __unmatched__", "POST /users"}},
+		{"status code without requests", url.Values{"status": {"418"}}, "1m", "inbound", 0, []string{}},
+		{"method case-insensitive", url.Values{"method": {"post"}}, "1m", "inbound", 1, []string{"POST /users"}},
+		{"path substring", url.Values{"path": {"USERS"}}, "1m", "inbound", 2, []string{"GET /users/:id", "POST /users"}},
+		{"filters combined", url.Values{"path": {"users"}, "status": {"2xx"}, "method": {"GET"}}, "1m", "inbound", 1, []string{"GET /users/:id"}},
+		{"sort by path", url.Values{"sort": {"path"}}, "1m", "inbound", 4, []string{"GET /health", "POST /users", "GET /users/:id", "GET __unmatched__"}},
+		{"sort descending", url.Values{"sort": {"-count"}}, "1m", "inbound", 4, []string{"GET /users/:id", "POST /users", "GET __unmatched__", "GET /health"}},
+		{"sort ascending", url.Values{"sort": {"max"}}, "1m", "inbound", 4, []string{"GET /health", "GET __unmatched__", "POST /users", "GET /users/:id"}},
+		{"sort descending method", url.Values{"sort": {"-method"}}, "1m", "inbound", 4, []string{"POST /users", "GET __unmatched__", "GET /users/:id", "GET /health"}},
+		{"limit", url.Values{"limit": {"2"}}, "1m", "inbound", 4, all[:2]},
+		{"offset", url.Values{"limit": {"2"}, "offset": {"3"}}, "1m", "inbound", 4, all[3:]},
+		{"offset past the end", url.Values{"offset": {"10"}}, "1m", "inbound", 4, []string{}},
+		{"largest limit", url.Values{"limit": {"1000"}}, "1m", "inbound", 4, all},
+		{"outbound", url.Values{"scope": {"outbound"}}, "1m", "outbound", 1, []string{"GET api.example.com/v1/items"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var page RoutesPage
+			if code := get(t, d, "api/v1/routes?"+tt.query.Encode(), &page); code != http.StatusOK {
+				t.Fatalf("status %d", code)
+			}
+			if page.Window != tt.window || page.Scope != tt.scope || page.Total != tt.total {
+				t.Errorf("window %q, scope %q, total %d, want %q, %q, %d", page.Window, page.Scope, page.Total, tt.window, tt.scope, tt.total)
+			}
+			if got := routeNames(page.Routes); !slices.Equal(got, tt.routes) {
+				t.Errorf("routes %q, want %q", got, tt.routes)
+			}
+		})
+	}
+}
+
+func TestAPIRoutesCounts(t *testing.T) {
+	d := testDashboard(false)
+	for _, window := range []string{"1m", "total"} {
+		var page RoutesPage
+		get(t, d, "api/v1/routes?path=/users/:id&window="+window, &page)
+		if len(page.Routes) != 1 {
+			t.Fatalf("%s: %d routes", window, len(page.Routes))
+		}
+		r := page.Routes[0]
+		if r.Count != 3 || r.ErrorCount != 1 || r.Status[200] != 2 || r.Status[500] != 1 || !r.HasError {
+			t.Errorf("%s: count %d, errors %d, status %v", window, r.Count, r.ErrorCount, r.Status)
+		}
+	}
+}
+
+func TestAPIBadRequests(t *testing.T) {
+	d := testDashboard(true)
+	for _, target := range []string{
+		"api/v1/routes?window=5m",
+		"api/v1/routes?scope=both",
+		"api/v1/routes?status=6xx",
+		"api/v1/routes?status=5XX",
+		"api/v1/routes?status=server",
+		"api/v1/routes?sort=speed",
+		"api/v1/routes?limit=0",
+		"api/v1/routes?limit=1001",
+		"api/v1/routes?limit=ten",
+		"api/v1/routes?offset=-1",
+		"api/v1/routes/GET/users/:id?scope=both",
+		"api/v1/history/GET/users/:id?scope=both",
+		"api/v1/requests/GET/users/:id?scope=both",
+		"api/v1/latency/GET/users/:id?window=5m",
+	} {
+		var body map[string]string
+		w := httptest.NewRecorder()
+		d.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, base+target, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", target, w.Code)
+			continue
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] == "" {
+			t.Errorf("%s: body %q has no error message", target, w.Body)
+		}
+	}
+}
+
+func TestAPINotFoundAndMethods(t *testing.T) {
+	d := testDashboard(true)
+	for _, target := range []string{
+		"api/v1/unknown",
+		"api/v1/routes/GET/nope",
+		"api/v1/routes/DELETE/users/:id",
+		"api/v1/routes/GET/api.example.com/v1/items", // outbound, but inbound scope asked
+		"api/v1/history/GET/nope",
+		"api/v1/requests/GET/nope",
+		"api/v1/latency/GET/nope",
+	} {
+		if code := get(t, d, target, nil); code != http.StatusNotFound {
+			t.Errorf("%s: status %d, want 404", target, code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	d.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, base+"api/v1/routes", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("POST: status %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestAPIWindows(t *testing.T) {
+	var windows []WindowInfo
+	get(t, testDashboard(false), "api/v1/windows", &windows)
+	want := []WindowInfo{{Name: "1m", Seconds: 60}, {Name: "total"}}
+	if !slices.Equal(windows, want) {
+		t.Errorf("windows %v, want %v", windows, want)
+	}
+}
+
+// TestAPIRouteParam checks that paths with and without a leading slash are found.
+func TestAPIRouteParam(t *testing.T) {
+	d := testDashboard(false)
+	for _, tt := range []struct {
+		target, path, scope string
+	}{
+		{"api/v1/routes/GET/users/:id", "/users/:id", "inbound"},
+		{"api/v1/routes/GET/health", "/health", "inbound"},
+		{"api/v1/routes/GET/__unmatched__", model.UnmatchedRoute, "inbound"},
+		{"api/v1/routes/GET/api.example.com/v1/items?scope=outbound", "api.example.com/v1/items", "outbound"},
+	} {
+		var detail RouteDetail
+		if code := get(t, d, tt.target, &detail); code != http.StatusOK {
+			t.Errorf("%s: status %d", tt.target, code)
+			continue
+		}
+		if detail.Method != "GET" || detail.Path != tt.path || detail.Scope != tt.scope {
+			t.Errorf("%s: got %s %q in %s", tt.target, detail.Method, detail.Path, detail.Scope)
+		}
+		if len(detail.Windows) != 2 || detail.Windows["1m"].Count == 0 || detail.Windows["total"].Count == 0 {
+			t.Errorf("%s: windows %v, want 1m and total with requests", tt.target, detail.Windows)
+		}
+	}
+}
+
+func TestAPIHistory(t *testing.T) {
+	var h RouteHistory
+	if code := get(t, testDashboard(true), "api/v1/history/GET/users/:id", &h); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if h.Path != "/users/:id" || h.Interval != 10 || h.Retention != 60 {
+		t.Errorf("path %q, interval %d, retention %d", h.Path, h.Interval, h.Retention)
+	}
+	count, status := 0, map[string]int{}
+	for i, p := range h.Points {
+		if i > 0 && p.Time.Sub(h.Points[i-1].Time) != 10*time.Second {
+			t.Errorf("points %d and %d are %s apart", i-1, i, p.Time.Sub(h.Points[i-1].Time))
+		}
+		count += p.Count
+		for class, n := range p.Status {
+			status[class] += n
+		}
+	}
+	if count != 3 || status["2xx"] != 2 || status["5xx"] != 1 {
+		t.Errorf("count %d, status %v, want 3 requests, 2xx twice and 5xx once", count, status)
+	}
+
+	w := httptest.NewRecorder()
+	testDashboard(false).Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, base+"api/v1/history/GET/users/:id", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("history disabled: status %d, want 404", w.Code)
+	}
+}
+
+func TestAPIRequests(t *testing.T) {
+	var rr RouteRequests
+	if code := get(t, testDashboard(false), "api/v1/requests/GET/users/:id", &rr); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	var slowest []float64
+	for _, s := range rr.Slowest {
+		slowest = append(slowest, s.Duration)
+	}
+	if !slices.Equal(slowest, []float64{300, 20, 8}) {
+		t.Errorf("slowest %v, want [300 20 8]", slowest)
+	}
+	if len(rr.Errors) != 1 || rr.Errors[0].Status != 500 || rr.Errors[0].TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("errors %+v, want the 500 with its trace ID", rr.Errors)
+	}
+}
+
+func TestAPILatency(t *testing.T) {
+	d := testDashboard(false)
+	for _, window := range []string{"", "1m", "total"} {
+		var h LatencyHistogram
+		if code := get(t, d, "api/v1/latency/GET/users/:id?window="+window, &h); code != http.StatusOK {
+			t.Fatalf("window %q: status %d", window, code)
+		}
+		if want := map[string]string{"": "1m"}[window]; want != "" && h.Window != want {
+			t.Errorf("default window %q, want %q", h.Window, want)
+		}
+		if !slices.Equal(h.Bounds, []float64{10, 100, 1000}) || !slices.Equal(h.Counts, []int{1, 1, 1, 0}) {
+			t.Errorf("window %q: bounds %v counts %v, want [10 100 1000] and [1 1 1 0]", window, h.Bounds, h.Counts)
+		}
+	}
+}
@@ -10,16 +10,16 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/aurieli333/goapimon/auth"
+	"github.com/aurieli333/goapimon/config"
 	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/store"
 	"github.com/aurieli333/goapimon/utility"
-
-	"github.com/influxdata/tdigest"
 )
 
-//go:embed template.html
+//go:embed template.html route.html
 var tmplFS embed.FS
 
 //go:embed static/*
@@ -41,21 +41,51 @@ type Row struct {
 	P99        float64     `json:"P99"`        // ms
 	Throughput float64     `json:"Throughput"` // rps
 	HasError   bool        `json:"Has_error"`
+
+	AvgReqBytes  float64 `json:"AvgReqBytes"`
+	AvgRespBytes float64 `json:"AvgRespBytes"`
+	P95RespBytes float64 `json:"P95RespBytes"` // -1 for total
+	Bytes        int64   `json:"Bytes"`        // request + response
+	Bandwidth    float64 `json:"Bandwidth"`    // bytes/s
+
+	GRPCStatus   map[string]int `json:"GRPCStatus,omitempty"` // gRPC routes only
+	MsgsReceived int64          `json:"MsgsReceived,omitempty"`
+	MsgsSent     int64          `json:"MsgsSent,omitempty"`
+
+	TransportErrors map[string]int `json:"TransportErrors,omitempty"` // outbound calls only
 }
 
+// ContentSecurityPolicy — sent with every dashboard response.
+// Scripts, styles and images are served from the embedded static/ directory,
+// the pages make no requests to other origins.
+const ContentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; img-src 'self'; " +
+	"connect-src 'self'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
 type Dashboard struct {
-	Mu      *sync.Mutex
+	Store   *store.Store
 	Windows []model.Window
-	Stats   map[string]map[string]*model.RouteStats
 	Enabled bool
+
+	// BasePath — where the dashboard is mounted, with leading and trailing slash.
+	// Static assets and the CSV export are served below it.
+	BasePath string
+
+	// PushInterval — how often live dashboards receive updates over Server-Sent Events
+	PushInterval time.Duration
+
+	// Auth — access rules for the pages, the JSON API and the CSV export, nil allows everyone
+	Auth *auth.Guard
+
+	hub hub // shared producer of live updates
 }
 
-func NewDashboard(mu *sync.Mutex, windows []model.Window, stats map[string]map[string]*model.RouteStats) *Dashboard {
+func NewDashboard(s *store.Store, windows []model.Window) *Dashboard {
 	return &Dashboard{
-		Mu:      mu,
-		Windows: windows,
-		Stats:   stats,
-		Enabled: false,
+		Store:        s,
+		Windows:      windows,
+		Enabled:      false,
+		BasePath:     config.DashboardPath,
+		PushInterval: DefaultPushInterval,
 	}
 }
 
@@ -64,27 +94,27 @@ func (d *Dashboard) Enable() {
 }
 
 func (d *Dashboard) exportCsv(w http.ResponseWriter, r *http.Request) {
-	d.Mu.Lock()
-	defer d.Mu.Unlock()
-
 	b := &bytes.Buffer{}
 	writer := csv.NewWriter(b)
-	writer.Write([]string{"window", "URL", "Method", "status", "count", "avg", "throughput"})
+	writer.Write([]string{"window", "URL", "Method", "status", "count", "avg", "throughput", "avg_req_bytes", "avg_resp_bytes", "bandwidth"})
 
-	data := d.calcData()
+	data := d.calcData(d.Store.Snapshot())
 
 	for window, rows := range data {
 		for _, row := range rows {
 			count := strconv.Itoa(row.Count)
 			avg := strconv.FormatFloat(row.Avg, 'f', 2, 64)
 			tp := strconv.FormatFloat(row.Throughput, 'f', 2, 64)
+			reqBytes := strconv.FormatFloat(row.AvgReqBytes, 'f', 0, 64)
+			respBytes := strconv.FormatFloat(row.AvgRespBytes, 'f', 0, 64)
+			bw := strconv.FormatFloat(row.Bandwidth, 'f', 2, 64)
 			var status string
 			for tStatus := range row.Status {
 				status = strconv.Itoa(tStatus)
 				break
 			}
 
-			writer.Write([]string{window, row.Path, row.Method, status, count, avg, tp})
+			writer.Write([]string{window, row.Path, row.Method, status, count, avg, tp, reqBytes, respBytes, bw})
 		}
 	}
 
@@ -97,48 +127,74 @@ func (d *Dashboard) exportCsv(w http.ResponseWriter, r *http.Request) {
 	w.Write(b.Bytes())
 }
 
-func (d *Dashboard) calcData() map[string][]Row {
+func (d *Dashboard) calcData(stats map[string]map[string]*model.RouteStats) map[string][]Row {
 	data := make(map[string][]Row)
 	now := time.Now()
 
 	// Windows
 	for _, win := range d.Windows {
-		rows := []Row{}
-		for method, paths := range d.Stats {
-			for path, s := range paths {
-				ws := utility.CalcWindowStats(s.Recent, win.Length, now)
-				if ws.Count == 0 {
-					continue
-				}
-				rows = append(rows, Row{
-					Method:     method,
-					Path:       path,
-					Count:      ws.Count,
-					ErrorCount: ws.ErrCount,
-					ErrorRate:  ws.ErrorRate,
-					Status:     ws.Status,
-					Avg:        ws.Avg,
-					Min:        ws.Min,
-					Max:        ws.Max,
-					P50:        ws.P50,
-					P90:        ws.P90,
-					P95:        ws.P95,
-					P99:        ws.P99,
-					Throughput: ws.RPS,
-					HasError:   ws.ErrCount > 0,
-				})
+		data[win.Name] = calcWindow(stats, win, now)
+	}
+
+	// Total
+	data["total"] = calcTotal(stats, now)
+
+	return data
+}
+
+// calcWindow builds rows of the routes with requests in win.
+func calcWindow(stats map[string]map[string]*model.RouteStats, win model.Window, now time.Time) []Row {
+	rows := []Row{}
+	for method, paths := range stats {
+		for path, s := range paths {
+			ws := utility.CalcWindowStats(s.Buckets, win.Length, now)
+			if ws.Count == 0 {
+				continue
 			}
+			rows = append(rows, Row{
+				Method:     method,
+				Path:       path,
+				Count:      ws.Count,
+				ErrorCount: ws.ErrCount,
+				ErrorRate:  ws.ErrorRate,
+				Status:     ws.Status,
+				Avg:        ws.Avg,
+				Min:        ws.Min,
+				Max:        ws.Max,
+				P50:        ws.P50,
+				P90:        ws.P90,
+				P95:        ws.P95,
+				P99:        ws.P99,
+				Throughput: ws.RPS,
+				HasError:   ws.ErrCount > 0,
+
+				AvgReqBytes:  ws.AvgReqBytes,
+				AvgRespBytes: ws.AvgRespBytes,
+				P95RespBytes: ws.P95RespBytes,
+				Bytes:        ws.ReqBytes + ws.RespBytes,
+				Bandwidth:    ws.Bandwidth,
+
+				GRPCStatus:   ws.GRPCStatus,
+				MsgsReceived: ws.MsgsReceived,
+				MsgsSent:     ws.MsgsSent,
+
+				TransportErrors: ws.TransportErrors,
+			})
 		}
-		data[win.Name] = rows
 	}
+	return rows
+}
 
-	// Total
+// calcTotal builds rows from lifetime aggregates.
+func calcTotal(stats map[string]map[string]*model.RouteStats, now time.Time) []Row {
 	rows := []Row{}
-	for method, paths := range d.Stats {
+	for method, paths := range stats {
 		for path, s := range paths {
-			avg := float64(0)
-			if s.TotalCount > 0 {
-				avg = float64(s.TotalTime.Milliseconds()) / float64(s.TotalCount)
+			avg, minMs, maxMs := float64(0), float64(-1), float64(-1)
+			if timed := s.TotalCount - s.TotalLongLived; timed > 0 {
+				avg = float64(s.TotalTime.Milliseconds()) / float64(timed)
+				minMs = float64(s.TotalMin.Milliseconds())
+				maxMs = float64(s.TotalMax.Milliseconds())
 			}
 
 			var rps float64 = -1
@@ -146,11 +202,17 @@ func (d *Dashboard) calcData() map[string][]Row {
 				rps = float64(s.TotalCount) / now.Sub(s.FirstSeen).Seconds()
 			}
 
-			// t-digest for quantiles
-			td := tdigest.NewWithCompression(1000)
-			for _, rec := range s.Recent {
-				ms := float64(rec.Duration.Milliseconds())
-				td.Add(ms, 1)
+			// lifetime quantiles
+			p50, p90, p95, p99 := utility.Quantiles(s.TotalLatency)
+
+			var avgReq, avgResp, bandwidth float64
+			totalBytes := s.TotalReqBytes + s.TotalRespBytes
+			if s.TotalCount > 0 {
+				avgReq = float64(s.TotalReqBytes) / float64(s.TotalCount)
+				avgResp = float64(s.TotalRespBytes) / float64(s.TotalCount)
+			}
+			if now.After(s.FirstSeen) {
+				bandwidth = float64(totalBytes) / now.Sub(s.FirstSeen).Seconds()
 			}
 
 			rows = append(rows, Row{
@@ -161,54 +223,92 @@ func (d *Dashboard) calcData() map[string][]Row {
 				ErrorRate:  float64(s.TotalErrorCount) / float64(s.TotalCount) * 100,
 				Status:     s.TotalStatus,
 				Avg:        avg,
-				Min:        float64(s.TotalMin.Milliseconds()),
-				Max:        float64(s.TotalMax.Milliseconds()),
-				P50:        td.Quantile(0.50),
-				P90:        td.Quantile(0.90),
-				P95:        td.Quantile(0.95),
-				P99:        td.Quantile(0.99),
+				Min:        minMs,
+				Max:        maxMs,
+				P50:        p50,
+				P90:        p90,
+				P95:        p95,
+				P99:        p99,
 				Throughput: rps,
 				HasError:   s.TotalErrorCount > 0,
+
+				AvgReqBytes:  avgReq,
+				AvgRespBytes: avgResp,
+				P95RespBytes: -1,
+				Bytes:        totalBytes,
+				Bandwidth:    bandwidth,
+
+				GRPCStatus:   s.TotalGRPCStatus,
+				MsgsReceived: s.TotalMsgsReceived,
+				MsgsSent:     s.TotalMsgsSent,
+
+				TransportErrors: s.TotalTransportErrors,
 			})
 		}
 	}
-	data["total"] = rows
-
-	return data
+	return rows
 }
 
 func (d *Dashboard) Handler() http.HandlerFunc {
 	staticFS, _ := fs.Sub(embeddedFiles, "static")
-	fileServer := http.StripPrefix("/__goapimon/static/", http.FileServer(http.FS(staticFS)))
+	fileServer := http.FileServer(http.FS(staticFS))
 	return func(w http.ResponseWriter, r *http.Request) {
 		if !d.Enabled {
 			http.NotFound(w, r)
 			return
 		}
+		base := d.BasePath
+		w.Header().Set("Content-Security-Policy", ContentSecurityPolicy)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if !d.Auth.Check(w, r) {
+			return
+		}
 
-		if r.URL.Path == "/__goapimon/export/csv" {
+		if r.URL.Path == base+"export/csv" {
 			// Serve CSV export
 			d.exportCsv(w, r)
 			return
 		}
 
-		if strings.HasPrefix(r.URL.Path, "/__goapimon/static/") {
-			fileServer.ServeHTTP(w, r)
+		if r.URL.Path == base+"route" {
+			d.routePage(w)
+			return
+		}
+
+		if rest, ok := strings.CutPrefix(r.URL.Path, base+apiPrefix); ok {
+			d.serveAPI(w, r, rest)
 			return
 		}
-		// Serve the main dashboard HTML
-		d.Mu.Lock()
-		data := d.calcData()
-		jsonData, err := json.Marshal(data)
+
+		if strings.HasPrefix(r.URL.Path, base+"static/") {
+			http.StripPrefix(base+"static/", fileServer).ServeHTTP(w, r)
+			return
+		}
+		// Serve the main dashboard HTML, its data is embedded as a JSON block
+		scopes := d.scopeData()
+		windows := make([]string, 0, len(d.Windows)+1)
+		for _, win := range d.Windows {
+			windows = append(windows, win.Name)
+		}
+		jsonData, err := json.Marshal(struct {
+			Windows  []string         `json:"windows"`
+			Inbound  map[string][]Row `json:"inbound"`
+			Outbound map[string][]Row `json:"outbound"`
+		}{
+			Windows:  append(windows, "total"),
+			Inbound:  scopes["inbound"],
+			Outbound: scopes["outbound"],
+		})
 		if err != nil {
 			http.Error(w, "Failed to encode data", http.StatusInternalServerError)
 			return
 		}
-		d.Mu.Unlock()
 
 		tmplData := struct {
+			Base string
 			Data template.JS
 		}{
+			Base: base,
 			Data: template.JS(jsonData),
 		}
 
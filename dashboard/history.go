@@ -0,0 +1,124 @@
+package dashboard
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+)
+
+// HistoryRow — one history point of a route, slots without requests are reported with zeros
+type HistoryRow struct {
+	Time      time.Time      `json:"time"`
+	Count     int            `json:"count"`
+	RPS       float64        `json:"rps"`
+	ErrorRate float64        `json:"error_rate"` // %
+	Avg       float64        `json:"avg"`        // ms
+	Max       float64        `json:"max"`        // ms
+	P50       float64        `json:"p50"`        // ms
+	P95       float64        `json:"p95"`        // ms
+	P99       float64        `json:"p99"`        // ms
+	Status    map[string]int `json:"status"`     // by class: 1xx-5xx, "none" for calls without a response
+}
+
+// RouteHistory — response of /api/v1/history/{method}/{path}, oldest point first
+type RouteHistory struct {
+	Method    string       `json:"method"`
+	Path      string       `json:"path"`
+	Scope     string       `json:"scope"`
+	Interval  int64        `json:"interval"`  // seconds per point
+	Retention int64        `json:"retention"` // seconds covered by points
+	Points    []HistoryRow `json:"points"`
+}
+
+var statusClasses = [6]string{"none", "1xx", "2xx", "3xx", "4xx", "5xx"}
+
+// apiHistory reports the time series of one route, rest is "{method}/{path}".
+func (d *Dashboard) apiHistory(w http.ResponseWriter, r *http.Request, rest string) {
+	outbound, scope, ok := parseScope(w, r.URL.Query().Get("scope"))
+	if !ok {
+		return
+	}
+	if d.Store.HistoryRetention() == 0 {
+		apiError(w, http.StatusNotFound, "history is disabled")
+		return
+	}
+
+	var points []model.HistoryPoint
+	method, path, found := routeParam(rest, func(method, path string) bool {
+		var ok bool
+		points, ok = d.Store.History(outbound, method, path)
+		return ok
+	})
+	if !found {
+		apiError(w, http.StatusNotFound, "route not found")
+		return
+	}
+
+	interval := d.Store.HistoryInterval()
+	retention := d.Store.HistoryRetention()
+	writeJSON(w, http.StatusOK, RouteHistory{
+		Method:    method,
+		Path:      path,
+		Scope:     scope,
+		Interval:  int64(interval / time.Second),
+		Retention: int64(retention / time.Second),
+		Points:    historyRows(points, interval, retention, time.Now()),
+	})
+}
+
+// historyRows turns sparse points into one row per interval up to now.
+func historyRows(points []model.HistoryPoint, interval, retention time.Duration, now time.Time) []HistoryRow {
+	end := now.Truncate(interval)
+	start := end.Add(-retention)
+	rows := make([]HistoryRow, 0, retention/interval+1)
+	for t := start; !t.After(end); t = t.Add(interval) {
+		row := HistoryRow{Time: t, Status: map[string]int{}}
+		for len(points) > 0 && points[0].Start.Before(t) {
+			points = points[1:]
+		}
+		if len(points) > 0 && points[0].Start.Equal(t) {
+			row = historyRow(points[0], interval)
+			points = points[1:]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func historyRow(p model.HistoryPoint, interval time.Duration) HistoryRow {
+	row := HistoryRow{
+		Time:      p.Start,
+		Count:     p.Count,
+		RPS:       float64(p.Count) / interval.Seconds(),
+		ErrorRate: float64(p.ErrCount) / float64(p.Count) * 100,
+		Max:       float64(p.Max.Nanoseconds()) / 1_000_000.,
+		P50:       p.P50,
+		P95:       p.P95,
+		P99:       p.P99,
+		Status:    make(map[string]int),
+	}
+	if timed := p.Count - p.LongLived; timed > 0 {
+		row.Avg = float64(p.Sum.Nanoseconds()) / 1_000_000. / float64(timed)
+	}
+	for class, cnt := range p.Classes {
+		if cnt > 0 {
+			row.Status[statusClasses[class]] = cnt
+		}
+	}
+	return row
+}
+
+// routePage serves the drill-down page of one route, selected by the method, path and scope query parameters.
+func (d *Dashboard) routePage(w http.ResponseWriter) {
+	tmpl, err := template.ParseFS(tmplFS, "route.html")
+	if err != nil {
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+	if err := tmpl.Execute(w, struct{ Base string }{Base: d.BasePath}); err != nil {
+		http.Error(w, "Render error", http.StatusInternalServerError)
+		return
+	}
+}
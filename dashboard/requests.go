@@ -0,0 +1,158 @@
+package dashboard
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+)
+
+// RequestSample — one request listed on the drill-down page
+type RequestSample struct {
+	Time           time.Time `json:"time"`
+	Duration       float64   `json:"duration"` // ms
+	Status         int       `json:"status,omitempty"`
+	GRPCCode       string    `json:"grpc_code,omitempty"`
+	TransportError string    `json:"transport_error,omitempty"`
+	Client         string    `json:"client,omitempty"`
+	TraceID        string    `json:"trace_id,omitempty"`
+}
+
+// RouteRequests — response of /api/v1/requests/{method}/{path}
+type RouteRequests struct {
+	Method  string          `json:"method"`
+	Path    string          `json:"path"`
+	Scope   string          `json:"scope"`
+	Slowest []RequestSample `json:"slowest"` // slowest first, within retention
+	Errors  []RequestSample `json:"errors"`  // newest first
+}
+
+// LatencyHistogram — response of /api/v1/latency/{method}/{path}.
+// Counts[i] holds requests up to Bounds[i], the last count is above every bound.
+type LatencyHistogram struct {
+	Method string    `json:"method"`
+	Path   string    `json:"path"`
+	Scope  string    `json:"scope"`
+	Window string    `json:"window"`
+	Bounds []float64 `json:"bounds"` // ms
+	Counts []int     `json:"counts"`
+}
+
+// apiRequests lists the slowest and the latest failed requests of one route, rest is "{method}/{path}".
+func (d *Dashboard) apiRequests(w http.ResponseWriter, r *http.Request, rest string) {
+	outbound, scope, ok := parseScope(w, r.URL.Query().Get("scope"))
+	if !ok {
+		return
+	}
+
+	var slowest, errors []model.Sample
+	method, path, found := routeParam(rest, func(method, path string) bool {
+		var ok bool
+		slowest, errors, ok = d.Store.Samples(outbound, method, path)
+		return ok
+	})
+	if !found {
+		apiError(w, http.StatusNotFound, "route not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RouteRequests{
+		Method:  method,
+		Path:    path,
+		Scope:   scope,
+		Slowest: requestSamples(slowest),
+		Errors:  requestSamples(errors),
+	})
+}
+
+func requestSamples(samples []model.Sample) []RequestSample {
+	out := make([]RequestSample, 0, len(samples))
+	for _, s := range samples {
+		out = append(out, RequestSample{
+			Time:           s.Timestamp,
+			Duration:       float64(s.Duration.Nanoseconds()) / 1_000_000.,
+			Status:         s.Status,
+			GRPCCode:       s.GRPCCode,
+			TransportError: s.TransportError,
+			Client:         s.Client,
+			TraceID:        s.TraceID,
+		})
+	}
+	return out
+}
+
+// apiLatency reports the latency distribution of one route in a window (default: first window),
+// over the histogram bounds used by the Prometheus exporter.
+func (d *Dashboard) apiLatency(w http.ResponseWriter, r *http.Request, rest string) {
+	q := r.URL.Query()
+	stats, scope, ok := d.scopeStats(w, q.Get("scope"))
+	if !ok {
+		return
+	}
+
+	var s *model.RouteStats
+	method, path, found := routeParam(rest, func(method, path string) bool {
+		s = stats[method][path]
+		return s != nil
+	})
+	if !found {
+		apiError(w, http.StatusNotFound, "route not found")
+		return
+	}
+
+	seconds := d.Store.HistogramBuckets()
+	bounds := make([]float64, len(seconds))
+	for i, b := range seconds {
+		bounds[i] = b * 1000
+	}
+
+	window := q.Get("window")
+	if window == "" {
+		window = "total"
+		if len(d.Windows) > 0 {
+			window = d.Windows[0].Name
+		}
+	}
+	var counts []int
+	if window == "total" {
+		counts = s.TotalHistogram
+	} else {
+		win, found := d.window(window)
+		if !found {
+			apiError(w, http.StatusBadRequest, "unknown window "+strconv.Quote(window))
+			return
+		}
+		counts = windowHistogram(s.Buckets, win.Length, time.Now(), bounds)
+	}
+
+	writeJSON(w, http.StatusOK, LatencyHistogram{
+		Method: method,
+		Path:   path,
+		Scope:  scope,
+		Window: window,
+		Bounds: bounds,
+		Counts: counts,
+	})
+}
+
+// windowHistogram spreads the latency digests of buckets overlapping (now-window, now]
+// over bounds, each centroid counts towards the bound of its mean.
+func windowHistogram(buckets []model.Bucket, window time.Duration, now time.Time, bounds []float64) []int {
+	counts := make([]float64, len(bounds)+1)
+	start := now.Add(-window)
+	for _, b := range buckets {
+		if !b.End.After(start) || b.Start.After(now) {
+			continue
+		}
+		for _, c := range b.Latency {
+			counts[sort.SearchFloat64s(bounds, c.Mean)] += c.Weight
+		}
+	}
+	out := make([]int, len(counts))
+	for i, c := range counts {
+		out[i] = int(c + 0.5)
+	}
+	return out
+}
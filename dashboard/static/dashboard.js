@@ -0,0 +1,595 @@
+const basePath = document.body.dataset.base;
+const initial = JSON.parse(document.getElementById('goapimon-data').textContent);
+const scopes = {inbound: initial.inbound, outbound: initial.outbound};
+const windows = initial.windows;
+let current = localStorage.getItem('goapimon-tab') || windows[0];
+if (!windows.includes(current)) current = windows[0];
+let scope = localStorage.getItem('goapimon-scope') || "inbound";
+let parsed = scopes[scope] || scopes.inbound;
+let source = null;
+let autoRefreshEnabled = localStorage.getItem('goapimon-autorefresh') === '1';
+
+function renderTabs() {
+  const tabs = document.getElementById('tabs');
+  tabs.innerHTML = windows.map(function(w) {
+    return '<button class="tab' + (w===current ? ' active' : '') + '" data-window="' + escapeHTML(w) + '">' + escapeHTML(w) + '</button>';
+  }).join('');
+  const scopeTabs = document.getElementById('scopes');
+  scopeTabs.innerHTML = Object.keys(scopes).map(function(s) {
+    return '<button class="tab' + (s===scope ? ' active' : '') + '" data-scope="' + s + '">' + s + '</button>';
+  }).join('');
+}
+
+function updateLogo() {
+  const isDark = document.body.classList.contains('dark');
+  const logo = document.getElementById('logo-img');
+  logo.src = basePath + (isDark ? 'static/goapimon_white.png' : 'static/goapimon_green.png');
+}
+
+
+function toggleView() {
+  const view = document.getElementById('viewSelector').value;
+  localStorage.setItem('goapimon-view', view); // ← Save selection
+  document.getElementById('tableWrap').hidden = view !== 'table';
+  document.getElementById('chartsWrap').hidden = view === 'table';
+  renderCharts();
+}
+
+// renderCharts draws the charts when they are shown, the table view leaves them alone
+function renderCharts() {
+  if (document.getElementById('viewSelector').value === 'table') return;
+  renderChart();
+  renderChartP95();
+  renderChartP99();
+  renderErrorChart();
+  renderStatusPieChart();
+  renderLatencyChart();
+}
+
+// renderView redraws the table and the shown charts after data or filters change
+function renderView() {
+  renderTable();
+  renderCharts();
+}
+
+
+function statusBadges(status) {
+  let html = '<span class="status-badges">';
+  const codes = Object.keys(status).map(Number).sort((a,b)=>a-b);
+  for (let i=0; i<codes.length; ++i) {
+    const code = codes[i];
+    const count = status[code];
+    let cls = 'badge-other';
+    if (code >= 200 && code < 300) cls = 'badge-2xx';
+    else if (code >= 300 && code < 400) cls = 'badge-3xx';
+    else if (code >= 400 && code < 500) cls = 'badge-4xx';
+    else if (code >= 500 && code < 600) cls = 'badge-5xx';
+    html += '<span class="badge ' + cls + '" title="' + code + ' status">' + code + ' <span class="badge-count">(' + count + ')</span></span>';
+  }
+  html += '</span>';
+  return html;
+}
+
+const grpcServerCodes = ['Unknown', 'DeadlineExceeded', 'Unimplemented', 'Internal', 'Unavailable', 'DataLoss'];
+
+function grpcBadges(row) {
+  let html = '<span class="status-badges">';
+  const codes = Object.keys(row.GRPCStatus).sort();
+  for (let i=0; i<codes.length; ++i) {
+    const code = codes[i];
+    let cls = 'badge-4xx';
+    if (code === 'OK') cls = 'badge-2xx';
+    else if (grpcServerCodes.includes(code)) cls = 'badge-5xx';
+    html += '<span class="badge ' + cls + '" title="gRPC ' + code + '">' + code + ' <span class="badge-count">(' + row.GRPCStatus[code] + ')</span></span>';
+  }
+  if (row.MsgsReceived || row.MsgsSent) {
+    html += '<span class="badge badge-other" title="stream messages received / sent">msgs ' + (row.MsgsReceived || 0) + ' / ' + (row.MsgsSent || 0) + '</span>';
+  }
+  html += '</span>';
+  return html;
+}
+
+function transportBadges(row) {
+  if (!row.TransportErrors) return '';
+  let html = '<span class="status-badges">';
+  const kinds = Object.keys(row.TransportErrors).sort();
+  for (let i=0; i<kinds.length; ++i) {
+    const kind = kinds[i];
+    html += '<span class="badge badge-5xx" title="no response: ' + kind + '">' + kind + ' <span class="badge-count">(' + row.TransportErrors[kind] + ')</span></span>';
+  }
+  html += '</span>';
+  return html;
+}
+
+function formatBytes(n) {
+  const units = ['B', 'KB', 'MB', 'GB'];
+  let i = 0;
+  while (n >= 1024 && i < units.length - 1) {
+    n /= 1024;
+    i++;
+  }
+  return (i === 0 ? Math.round(n) : n.toFixed(1)) + ' ' + units[i];
+}
+
+function escapeHTML(s) {
+  return String(s).replace(/[&<>"']/g, function(c) {
+    return {'&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'}[c];
+  });
+}
+
+// routeLink — path linking to the route's drill-down page
+function routeLink(row) {
+  const href = basePath + 'route?' + new URLSearchParams({method: row.Method, path: row.Path, scope: scope});
+  return '<a class="route-link" href="' + escapeHTML(href) + '">' + escapeHTML(row.Path) + '</a>';
+}
+
+function renderTable() {
+  const rows = parsed[current] || [];
+  const pathVal = document.getElementById('pathFilter').value.toLowerCase();
+  const methodVal = document.getElementById('methodFilter').value;
+  const methods = new Set();
+  let html = '<table><thead><tr><th>Method</th><th>Path</th><th>Count</th><th>Error count</th><th>Error rate %</th><th>Status</th><th>Avg ms</th><th>Min ms</th><th>Max ms</th><th>RPS</th><th>p50 ms</th><th>p90 ms</th><th>p95 ms</th><th>p99 ms</th><th>Avg req</th><th>Avg resp</th><th>p95 resp</th><th>Bandwidth</th></tr></thead><tbody>';
+  for (let i=0; i<rows.length; ++i) {
+    const row = rows[i];
+    methods.add(row.Method);
+    if (pathVal && row.Path.toLowerCase().indexOf(pathVal) === -1) continue;
+    if (methodVal && row.Method !== methodVal) continue;
+    html += '<tr' + (row.HasError ? ' class="error"' : '') + '><td>' + row.Method + '</td><td>' + routeLink(row) + '</td><td>' + row.Count + '</td><td>' + row.ErrorCount + '</td><td>' + row.ErrorRate + '</td><td class="status">' + (row.GRPCStatus ? grpcBadges(row) : statusBadges(row.Status) + transportBadges(row)) + '</td><td>' + row.Avg.toFixed(2) + '</td><td>' + (row.Min === -1 ? 'N/A' : row.Min.toFixed(2)) + '</td><td>' + (row.Max === -1 ? 'N/A' : row.Max.toFixed(2)) + '</td><td>' + (row.Throughput === -1 ? 'N/A' : row.Throughput.toFixed(2)) + '</td><td>' + row.P50.toFixed(2) + '</td><td>' + row.P90.toFixed(2) + '</td><td>' + row.P95.toFixed(2) + '</td><td>' + row.P99.toFixed(2) + '</td><td>' + formatBytes(row.AvgReqBytes) + '</td><td>' + formatBytes(row.AvgRespBytes) + '</td><td>' + (row.P95RespBytes === -1 ? 'N/A' : formatBytes(row.P95RespBytes)) + '</td><td>' + formatBytes(row.Bandwidth) + '/s</td></tr>';
+  }
+  html += '</tbody></table>';
+  document.getElementById('tableWrap').innerHTML = html;
+  document.querySelectorAll('#tableWrap tbody tr').forEach(function(tr) {
+    tr.onclick = function(e) {
+      if (e.target.closest('a')) return;
+      location.href = tr.querySelector('a.route-link').href;
+    };
+  });
+  // Fill method filter
+  const sel = document.getElementById('methodFilter');
+  const prev = sel.value;
+  sel.innerHTML = '<option value="">All</option>' + Array.from(methods).sort().map(function(m){return '<option value="'+m+'">'+m+'</option>';}).join('');
+  sel.value = prev;
+}
+
+// upsertChart updates an existing chart in place instead of rebuilding it
+function upsertChart(chart, ctx, config) {
+  if (!chart || chart.config.type !== config.type) {
+    if (chart) chart.destroy();
+    return new MiniChart(ctx, config);
+  }
+  chart.data.labels = config.data.labels;
+  chart.data.datasets.length = config.data.datasets.length;
+  config.data.datasets.forEach(function(ds, i) {
+    if (chart.data.datasets[i]) Object.assign(chart.data.datasets[i], ds);
+    else chart.data.datasets[i] = ds;
+  });
+  chart.update('none');
+  return chart;
+}
+
+function renderChart() {
+  const ctx = document.getElementById('rpsChart').getContext('2d');
+  const rows = parsed[current] || [];
+
+  const pathVal = document.getElementById('pathFilter').value.toLowerCase();
+  const methodVal = document.getElementById('methodFilter').value;
+
+  const filtered = rows.filter(row => {
+    if (pathVal && !row.Path.toLowerCase().includes(pathVal)) return false;
+    if (methodVal && row.Method !== methodVal) return false;
+    return true;
+  });
+
+  const labels = filtered.map(r => r.Path);
+  const data = filtered.map(r => r.Throughput === -1 ? 0 : r.Throughput);
+
+  window.rpsChartInstance = upsertChart(window.rpsChartInstance, ctx, {
+    type: 'bar',
+    data: {
+      labels: labels,
+      datasets: [{
+        label: 'Requests Per Second',
+        data: data,
+        backgroundColor: 'rgba(0, 123, 255, 0.6)',
+        borderColor: 'rgba(0, 123, 255, 1)',
+        borderWidth: 1
+      }]
+    },
+    options: {
+      responsive: true,
+      plugins: {
+        legend: { display: true },
+        tooltip: {
+          callbacks: {
+            label: function(ctx) {
+              return `${ctx.dataset.label}: ${ctx.parsed.y.toFixed(2)}`;
+            }
+          }
+        }
+      },
+      scales: {
+        x: { ticks: { autoSkip: false } },
+        y: {
+          beginAtZero: true,
+          title: { display: true, text: 'RPS' }
+        }
+      }
+    }
+  });
+}
+
+function renderChartP95() {
+  const ctx = document.getElementById('p95Chart').getContext('2d');
+  const rows = parsed[current] || [];
+
+  const pathVal = document.getElementById('pathFilter').value.toLowerCase();
+  const methodVal = document.getElementById('methodFilter').value;
+
+  const filtered = rows.filter(row => {
+    if (pathVal && !row.Path.toLowerCase().includes(pathVal)) return false;
+    if (methodVal && row.Method !== methodVal) return false;
+    return true;
+  });
+
+  const labels = filtered.map(r => r.Path);
+  const data = filtered.map(r => r.P95 === -1 ? 0 : r.P95);
+
+  window.p95ChartInstance = upsertChart(window.p95ChartInstance, ctx, {
+    type: 'bar',
+    data: {
+      labels: labels,
+      datasets: [{
+        label: 'p95',
+        data: data,
+        backgroundColor: 'rgba(0, 123, 255, 0.6)',
+        borderColor: 'rgba(0, 123, 255, 1)',
+        borderWidth: 1
+      }]
+    },
+    options: {
+      responsive: true,
+      plugins: {
+        legend: { display: true },
+        tooltip: {
+          callbacks: {
+            label: function(ctx) {
+              return `${ctx.dataset.label}: ${ctx.parsed.y.toFixed(2)}`;
+            }
+          }
+        }
+      },
+      scales: {
+        x: { ticks: { autoSkip: false } },
+        y: {
+          beginAtZero: true,
+          title: { display: true, text: 'ms' }
+        }
+      }
+    }
+  });
+}
+
+function renderChartP99() {
+  const ctx = document.getElementById('p99Chart').getContext('2d');
+  const rows = parsed[current] || [];
+
+  const pathVal = document.getElementById('pathFilter').value.toLowerCase();
+  const methodVal = document.getElementById('methodFilter').value;
+
+  const filtered = rows.filter(row => {
+    if (pathVal && !row.Path.toLowerCase().includes(pathVal)) return false;
+    if (methodVal && row.Method !== methodVal) return false;
+    return true;
+  });
+
+  const labels = filtered.map(r => r.Path);
+  const data = filtered.map(r => r.P99 === -1 ? 0 : r.P99);
+
+  window.p99ChartInstance = upsertChart(window.p99ChartInstance, ctx, {
+    type: 'bar',
+    data: {
+      labels: labels,
+      datasets: [{
+        label: 'p99',
+        data: data,
+        backgroundColor: 'rgba(0, 123, 255, 0.6)',
+        borderColor: 'rgba(0, 123, 255, 1)',
+        borderWidth: 1
+      }]
+    },
+    options: {
+      responsive: true,
+      plugins: {
+        legend: { display: true },
+        tooltip: {
+          callbacks: {
+            label: function(ctx) {
+              return `${ctx.dataset.label}: ${ctx.parsed.y.toFixed(2)}`;
+            }
+          }
+        }
+      },
+      scales: {
+        x: { ticks: { autoSkip: false } },
+        y: {
+          beginAtZero: true,
+          title: { display: true, text: 'ms' }
+        }
+      }
+    }
+  });
+}
+
+function renderErrorChart() {
+  const ctx = document.getElementById('errorChart').getContext('2d');
+  const rows = parsed[current] || [];
+  const filtered = rows.filter(row => {
+    const pathVal = document.getElementById('pathFilter').value.toLowerCase();
+    const methodVal = document.getElementById('methodFilter').value;
+    if (pathVal && !row.Path.toLowerCase().includes(pathVal)) return false;
+    if (methodVal && row.Method !== methodVal) return false;
+    return true;
+  });
+
+  const labels = filtered.map(r => r.Path);
+  const data = filtered.map(r => r.ErrorCount);
+
+  window.errorChartInstance = upsertChart(window.errorChartInstance, ctx, {
+    type: 'bar',
+    data: {
+      labels,
+      datasets: [{
+        label: 'Error Count',
+        data,
+        backgroundColor: 'rgba(255, 99, 132, 0.6)',
+        borderColor: 'rgba(255, 99, 132, 1)',
+        borderWidth: 1
+      }]
+    },
+    options: {
+      responsive: true,
+      plugins: { legend: { display: true } },
+      scales: {
+        y: {
+          beginAtZero: true,
+          title: { display: true, text: 'Errors' }
+        }
+      }
+    }
+  });
+}
+
+function renderStatusPieChart() {
+  const ctx = document.getElementById('statusPieChart').getContext('2d');
+  const rows = parsed[current] || [];
+  const statusTotals = {};
+
+  rows.forEach(row => {
+    const pathVal = document.getElementById('pathFilter').value.toLowerCase();
+    const methodVal = document.getElementById('methodFilter').value;
+    if (pathVal && !row.Path.toLowerCase().includes(pathVal)) return;
+    if (methodVal && row.Method !== methodVal) return;
+    Object.entries(row.Status).forEach(([code, count]) => {
+      statusTotals[code] = (statusTotals[code] || 0) + count;
+    });
+    Object.entries(row.TransportErrors || {}).forEach(([kind, count]) => {
+      statusTotals[kind] = (statusTotals[kind] || 0) + count;
+    });
+  });
+
+  const codes = Object.keys(statusTotals).sort();
+  const counts = codes.map(c => statusTotals[c]);
+
+  window.statusPieChartInstance = upsertChart(window.statusPieChartInstance, ctx, {
+    type: 'pie',
+    data: {
+      labels: codes,
+      datasets: [{
+        label: 'Status Codes',
+        data: counts,
+        backgroundColor: codes.map(c => {
+          if (c.startsWith('2')) return '#2ecc40';
+          if (c.startsWith('3')) return '#3498db';
+          if (c.startsWith('4')) return '#f1c40f';
+          if (c.startsWith('5')) return '#e74c3c';
+          if (isNaN(c)) return '#c0392b'; // outbound call without response
+          return '#888';
+        })
+      }]
+    },
+    options: {
+      responsive: true,
+      plugins: {
+        legend: { position: 'bottom' },
+        tooltip: {
+          callbacks: {
+            label: ctx => `${ctx.label}: ${ctx.parsed} responses`
+          }
+        }
+      }
+    }
+  });
+}
+
+function renderLatencyChart() {
+  const ctx = document.getElementById('latencyChart').getContext('2d');
+  const rows = parsed[current] || [];
+  const filtered = rows.filter(row => {
+    const pathVal = document.getElementById('pathFilter').value.toLowerCase();
+    const methodVal = document.getElementById('methodFilter').value;
+    if (pathVal && !row.Path.toLowerCase().includes(pathVal)) return false;
+    if (methodVal && row.Method !== methodVal) return false;
+    return true;
+  });
+
+  const labels = filtered.map(r => r.Path);
+  const avg = filtered.map(r => r.Avg.toFixed(2));
+  const min = filtered.map(r => r.Min === -1 ? 0 : r.Min.toFixed(2));
+  const max = filtered.map(r => r.Max === -1 ? 0 : r.Max.toFixed(2));
+
+  window.latencyChartInstance = upsertChart(window.latencyChartInstance, ctx, {
+    type: 'line',
+    data: {
+      labels,
+      datasets: [
+        {
+          label: 'Avg ms',
+          data: avg,
+          borderColor: '#007bff',
+          backgroundColor: 'rgba(0, 123, 255, 0.2)',
+          tension: 0.4
+        },
+        {
+          label: 'Min ms',
+          data: min,
+          borderColor: '#28a745',
+          backgroundColor: 'rgba(40, 167, 69, 0.2)',
+          tension: 0.4
+        },
+        {
+          label: 'Max ms',
+          data: max,
+          borderColor: '#dc3545',
+          backgroundColor: 'rgba(220, 53, 69, 0.2)',
+          tension: 0.4
+        }
+      ]
+    },
+    options: {
+      responsive: true,
+      plugins: { legend: { position: 'bottom' } },
+      scales: {
+        y: {
+          beginAtZero: true,
+          title: { display: true, text: 'Milliseconds' }
+        }
+      }
+    }
+  });
+}
+
+
+function switchTab(w) {
+  current = w;
+  localStorage.setItem('goapimon-tab', w);
+  renderTabs();
+  renderView();
+}
+
+function switchScope(s) {
+  scope = s;
+  parsed = scopes[s];
+  localStorage.setItem('goapimon-scope', s);
+  renderTabs();
+  renderTable();
+  toggleView();
+}
+
+document.getElementById('pathFilter').oninput = renderView;
+document.getElementById('methodFilter').onchange = renderView;
+document.getElementById('viewSelector').onchange = toggleView;
+document.getElementById('refreshButton').onclick = refresh;
+document.getElementById('csvButton').onclick = downloadCSV;
+document.getElementById('theme-toggle').onclick = toggleTheme;
+document.getElementById('tabs').onclick = function(e) {
+  const tab = e.target.closest('[data-window]');
+  if (tab) switchTab(tab.dataset.window);
+};
+document.getElementById('scopes').onclick = function(e) {
+  const tab = e.target.closest('[data-scope]');
+  if (tab) switchScope(tab.dataset.scope);
+};
+
+function refresh() {
+  localStorage.setItem('goapimon-tab', current);
+  location.reload();
+}
+
+function downloadCSV() {
+  window.open(basePath + 'export/csv', '_blank')
+}
+
+function routeKey(row) {
+  return row.Method + ' ' + row.Path;
+}
+
+// applyDelta merges changed and removed rows pushed by the server
+function applyDelta(delta) {
+  Object.keys(delta).forEach(function(s) {
+    Object.keys(delta[s]).forEach(function(w) {
+      const change = delta[s][w];
+      const rows = {};
+      (scopes[s][w] || []).forEach(function(r) { rows[routeKey(r)] = r; });
+      (change.del || []).forEach(function(r) { delete rows[routeKey(r)]; });
+      (change.set || []).forEach(function(r) { rows[routeKey(r)] = r; });
+      scopes[s][w] = Object.values(rows);
+    });
+  });
+}
+
+function rerender() {
+  parsed = scopes[scope] || scopes.inbound;
+  renderView();
+}
+
+// autoRefresh follows the server stream instead of reloading the page
+function autoRefresh() {
+  const status = document.getElementById('autorefresh');
+  if (source) {
+    source.close();
+    source = null;
+  }
+  if (!autoRefreshEnabled) {
+    status.textContent = '';
+    return;
+  }
+  source = new EventSource(basePath + 'api/v1/stream');
+  source.addEventListener('snapshot', function(e) {
+    const d = JSON.parse(e.data);
+    scopes.inbound = d.inbound;
+    scopes.outbound = d.outbound;
+    rerender();
+    status.textContent = 'Live';
+  });
+  source.addEventListener('delta', function(e) {
+    applyDelta(JSON.parse(e.data));
+    rerender();
+    status.textContent = 'Live, updated ' + new Date().toLocaleTimeString();
+  });
+  source.onerror = function() {
+    status.textContent = 'Reconnecting…';
+  };
+}
+
+document.getElementById('autorefreshbox').checked = autoRefreshEnabled;
+document.getElementById('autorefreshbox').onchange = function() {
+  autoRefreshEnabled = this.checked;
+  localStorage.setItem('goapimon-autorefresh', autoRefreshEnabled ? '1' : '');
+  autoRefresh();
+};
+
+function toggleTheme() {
+  const body = document.body;
+  const btn = document.getElementById('theme-toggle');
+  const dark = body.classList.toggle('dark');
+  btn.textContent = dark ? 'Light' : 'Dark';
+  localStorage.setItem('goapimon-theme', dark ? 'dark' : '');
+  updateLogo();
+}
+
+(function(){
+  if(localStorage.getItem('goapimon-theme')==='dark') {
+    document.body.classList.add('dark');
+    document.getElementById('theme-toggle').textContent = 'Light';
+    updateLogo();
+  }
+})();
+
+renderTabs();
+renderTable();
+autoRefresh();
+
+
+const savedView = localStorage.getItem('goapimon-view') || 'table';
+document.getElementById('viewSelector').value = savedView;
+toggleView();
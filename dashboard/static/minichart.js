@@ -0,0 +1,477 @@
+// minichart.js — small canvas charts bundled with the goapimon dashboard.
+//
+// This is not Chart.js. It is goapimon's own renderer, written so the dashboard pages
+// work offline and under a strict Content-Security-Policy. Its constructor takes a
+// Chart.js-style config, but it only implements what the dashboard pages use:
+//
+//   const chart = new MiniChart(ctx, {type: 'bar' | 'line' | 'pie', data: {labels, datasets}, options});
+//   chart.data.datasets[0].data = [...]; chart.update(); chart.destroy();
+//
+// Supported options: plugins.title, plugins.legend (display, position),
+// plugins.tooltip.callbacks.label, scales.{x,y}.stacked, scales.x.ticks.autoSkip,
+// scales.y.beginAtZero, scales.y.suggestedMax and scales.y.title.
+// Datasets use label, data, backgroundColor, borderColor, borderWidth, fill, tension and pointRadius.
+(function() {
+  'use strict';
+
+  const palette = ['#1f777e', '#3498db', '#f39c12', '#e74c3c', '#2ecc40', '#8e44ad', '#888'];
+  const font = '12px system-ui, sans-serif';
+  const boldFont = 'bold 13px system-ui, sans-serif';
+  const gridColor = 'rgba(128, 128, 128, 0.2)';
+
+  function option(obj, path, def) {
+    const keys = path.split('.');
+    for (let i = 0; i < keys.length; i++) {
+      if (obj == null) return def;
+      obj = obj[keys[i]];
+    }
+    return obj === undefined ? def : obj;
+  }
+
+  function value(ds, i) {
+    const v = Number(ds.data[i]);
+    return isFinite(v) ? v : 0;
+  }
+
+  function colorAt(color, i, fallback) {
+    if (Array.isArray(color)) return color[i % color.length] || fallback;
+    return color || fallback;
+  }
+
+  function format(v) {
+    return Number.isInteger(v) ? String(v) : v.toFixed(2);
+  }
+
+  // niceStep rounds a raw tick step to 1, 2 or 5 times a power of ten
+  function niceStep(raw) {
+    const pow = Math.pow(10, Math.floor(Math.log10(raw)));
+    const f = raw / pow;
+    return (f <= 1 ? 1 : f <= 2 ? 2 : f <= 5 ? 5 : 10) * pow;
+  }
+
+  function truncate(ctx, text, width) {
+    if (ctx.measureText(text).width <= width) return text;
+    while (text.length > 1 && ctx.measureText(text + '…').width > width) {
+      text = text.slice(0, -1);
+    }
+    return text + '…';
+  }
+
+  function MiniChart(item, config) {
+    this.canvas = item.canvas || item;
+    this.ctx = this.canvas.getContext('2d');
+    this.config = config;
+    this.data = config.data;
+    this.options = config.options || {};
+    this.hover = null;
+
+    this.canvas.style.display = 'block';
+    this.canvas.style.width = '100%';
+
+    const chart = this;
+    this.onMove = function(e) {
+      const r = chart.canvas.getBoundingClientRect();
+      chart.hover = {x: e.clientX - r.left, y: e.clientY - r.top};
+      chart.draw();
+    };
+    this.onLeave = function() {
+      chart.hover = null;
+      chart.draw();
+    };
+    this.canvas.addEventListener('mousemove', this.onMove);
+    this.canvas.addEventListener('mouseleave', this.onLeave);
+    if (window.ResizeObserver) {
+      this.observer = new ResizeObserver(function() { chart.draw(); });
+      this.observer.observe(this.canvas.parentElement || this.canvas);
+    }
+    this.draw();
+  }
+
+  MiniChart.prototype.update = function() {
+    this.options = this.config.options || {};
+    this.draw();
+  };
+
+  MiniChart.prototype.destroy = function() {
+    this.canvas.removeEventListener('mousemove', this.onMove);
+    this.canvas.removeEventListener('mouseleave', this.onLeave);
+    if (this.observer) this.observer.disconnect();
+    this.ctx.setTransform(1, 0, 0, 1, 0, 0);
+    this.ctx.clearRect(0, 0, this.canvas.width, this.canvas.height);
+  };
+
+  MiniChart.prototype.draw = function() {
+    const canvas = this.canvas;
+    const width = canvas.clientWidth;
+    if (!width) return; // hidden
+    const height = Math.round(width / (this.config.type === 'pie' ? 1 : 2));
+    const ratio = window.devicePixelRatio || 1;
+    if (canvas.width !== Math.round(width * ratio) || canvas.height !== Math.round(height * ratio)) {
+      canvas.width = Math.round(width * ratio);
+      canvas.height = Math.round(height * ratio);
+      canvas.style.height = height + 'px';
+    }
+
+    const ctx = this.ctx;
+    ctx.setTransform(ratio, 0, 0, ratio, 0, 0);
+    ctx.clearRect(0, 0, width, height);
+    ctx.font = font;
+    ctx.textBaseline = 'middle';
+    this.color = getComputedStyle(canvas).color;
+    this.width = width;
+    this.height = height;
+
+    let area = {left: 6, top: 6, right: width - 6, bottom: height - 6};
+    area = this.drawTitle(area);
+    area = this.drawLegend(area);
+    const tip = this.config.type === 'pie' ? this.drawPie(area) : this.drawCartesian(area);
+    if (tip) this.drawTooltip(tip);
+  };
+
+  MiniChart.prototype.drawTitle = function(area) {
+    const title = option(this.options, 'plugins.title', {});
+    if (!title.display || !title.text) return area;
+    const ctx = this.ctx;
+    ctx.font = boldFont;
+    ctx.fillStyle = this.color;
+    ctx.textAlign = 'center';
+    ctx.fillText(title.text, (area.left + area.right) / 2, area.top + 9);
+    ctx.font = font;
+    return Object.assign({}, area, {top: area.top + 22});
+  };
+
+  // legendItems — one entry per slice for pie charts, per dataset otherwise
+  MiniChart.prototype.legendItems = function() {
+    const datasets = this.data.datasets || [];
+    if (this.config.type === 'pie') {
+      const ds = datasets[0] || {data: []};
+      return (this.data.labels || []).map(function(label, i) {
+        return {text: String(label), color: colorAt(ds.backgroundColor, i, palette[i % palette.length])};
+      });
+    }
+    const line = this.config.type === 'line';
+    return datasets.map(function(ds, i) {
+      const fallback = palette[i % palette.length];
+      return {
+        text: ds.label || '',
+        color: line ? colorAt(ds.borderColor, 0, fallback) : colorAt(ds.backgroundColor, 0, fallback)
+      };
+    });
+  };
+
+  MiniChart.prototype.drawLegend = function(area) {
+    const legend = option(this.options, 'plugins.legend', {});
+    if (legend.display === false) return area;
+    const items = this.legendItems().filter(function(it) { return it.text; });
+    if (!items.length) return area;
+
+    const ctx = this.ctx;
+    const maxWidth = area.right - area.left;
+    const rows = [[]];
+    let rowWidth = 0;
+    items.forEach(function(it) {
+      it.width = 16 + Math.min(ctx.measureText(it.text).width, maxWidth - 16);
+      if (rowWidth && rowWidth + it.width + 12 > maxWidth) {
+        rows.push([]);
+        rowWidth = 0;
+      }
+      rows[rows.length - 1].push(it);
+      rowWidth += it.width + 12;
+    });
+
+    const bottom = legend.position === 'bottom';
+    let y = bottom ? area.bottom - rows.length * 18 + 9 : area.top + 9;
+    ctx.textAlign = 'left';
+    rows.forEach(function(row) {
+      const total = row.reduce(function(sum, it) { return sum + it.width + 12; }, -12);
+      let x = (area.left + area.right - total) / 2;
+      row.forEach(function(it) {
+        ctx.fillStyle = it.color;
+        ctx.fillRect(x, y - 5, 12, 10);
+        ctx.fillStyle = this.color;
+        ctx.fillText(truncate(ctx, it.text, it.width - 16), x + 16, y);
+        x += it.width + 12;
+      }, this);
+      y += 18;
+    }, this);
+
+    const used = rows.length * 18 + 4;
+    return bottom ? Object.assign({}, area, {bottom: area.bottom - used}) : Object.assign({}, area, {top: area.top + used});
+  };
+
+  MiniChart.prototype.drawCartesian = function(area) {
+    const ctx = this.ctx;
+    const type = this.config.type;
+    const labels = this.data.labels || [];
+    const datasets = this.data.datasets || [];
+    const n = labels.length;
+    const stacked = option(this.options, 'scales.y.stacked', false) || option(this.options, 'scales.x.stacked', false);
+
+    // y range
+    let lo = Infinity;
+    let hi = -Infinity;
+    for (let i = 0; i < n; i++) {
+      let pos = 0;
+      let neg = 0;
+      datasets.forEach(function(ds) {
+        const v = value(ds, i);
+        if (stacked) {
+          if (v >= 0) pos += v; else neg += v;
+        } else {
+          lo = Math.min(lo, v);
+          hi = Math.max(hi, v);
+        }
+      });
+      if (stacked) {
+        lo = Math.min(lo, neg);
+        hi = Math.max(hi, pos);
+      }
+    }
+    if (!isFinite(lo)) {
+      lo = 0;
+      hi = 1;
+    }
+    if (option(this.options, 'scales.y.beginAtZero', false) || type === 'bar') lo = Math.min(lo, 0);
+    hi = Math.max(hi, option(this.options, 'scales.y.suggestedMax', hi));
+    if (hi === lo) hi = lo + 1;
+    const step = niceStep((hi - lo) / 5);
+    lo = Math.floor(lo / step) * step;
+    hi = Math.ceil(hi / step) * step;
+
+    const ticks = [];
+    for (let t = lo; t <= hi + step / 2; t += step) ticks.push(Number(t.toPrecision(12)));
+    const tickWidth = Math.max.apply(null, ticks.map(function(t) { return ctx.measureText(format(t)).width; }));
+    const yTitle = option(this.options, 'scales.y.title', {});
+    const titleWidth = yTitle.display && yTitle.text ? 16 : 0;
+
+    // x labels, rotated when they don't fit their slot
+    const plotLeft = area.left + titleWidth + tickWidth + 6;
+    const slot = n ? (area.right - plotLeft) / n : 0;
+    const labelWidth = n ? Math.max.apply(null, labels.map(function(l) { return ctx.measureText(String(l)).width; })) : 0;
+    const rotate = labelWidth > slot - 4;
+    const maxLabel = Math.min(labelWidth, 90);
+    const labelHeight = n ? (rotate ? maxLabel * 0.71 + 12 : 16) : 0;
+    let every = 1;
+    if (rotate && option(this.options, 'scales.x.ticks.autoSkip', true)) every = Math.ceil(14 / Math.max(slot, 1));
+
+    const plot = {left: plotLeft, top: area.top + 4, right: area.right, bottom: area.bottom - labelHeight};
+    const y = function(v) {
+      return plot.bottom - (v - lo) / (hi - lo) * (plot.bottom - plot.top);
+    };
+
+    // grid and y ticks
+    ctx.textAlign = 'right';
+    ticks.forEach(function(t) {
+      ctx.strokeStyle = gridColor;
+      ctx.beginPath();
+      ctx.moveTo(plot.left, Math.round(y(t)) + 0.5);
+      ctx.lineTo(plot.right, Math.round(y(t)) + 0.5);
+      ctx.stroke();
+      ctx.fillStyle = this.color;
+      ctx.fillText(format(t), plot.left - 6, y(t));
+    }, this);
+    if (titleWidth) {
+      ctx.save();
+      ctx.translate(area.left + 7, (plot.top + plot.bottom) / 2);
+      ctx.rotate(-Math.PI / 2);
+      ctx.textAlign = 'center';
+      ctx.fillText(yTitle.text, 0, 0);
+      ctx.restore();
+    }
+
+    // x labels
+    labels.forEach(function(l, i) {
+      if (i % every) return;
+      const x = plot.left + slot * (i + 0.5);
+      ctx.fillStyle = this.color;
+      if (rotate) {
+        ctx.save();
+        ctx.translate(x, plot.bottom + 6);
+        ctx.rotate(-Math.PI / 4);
+        ctx.textAlign = 'right';
+        ctx.fillText(truncate(ctx, String(l), maxLabel), 0, 0);
+        ctx.restore();
+      } else {
+        ctx.textAlign = 'center';
+        ctx.fillText(String(l), x, plot.bottom + 9);
+      }
+    }, this);
+
+    let index = -1;
+    if (this.hover && this.hover.x >= plot.left && this.hover.x < plot.right && this.hover.y >= plot.top && this.hover.y <= plot.bottom) {
+      index = Math.floor((this.hover.x - plot.left) / slot);
+      ctx.fillStyle = gridColor;
+      ctx.fillRect(plot.left + slot * index, plot.top, slot, plot.bottom - plot.top);
+    }
+
+    if (type === 'bar') {
+      const group = slot * 0.8;
+      const barWidth = stacked ? group : group / Math.max(datasets.length, 1);
+      for (let i = 0; i < n; i++) {
+        let pos = 0;
+        let neg = 0;
+        datasets.forEach(function(ds, d) {
+          const v = value(ds, i);
+          let from = 0;
+          if (stacked) {
+            from = v >= 0 ? pos : neg;
+            if (v >= 0) pos += v; else neg += v;
+          }
+          const x = plot.left + slot * i + (slot - group) / 2 + (stacked ? 0 : barWidth * d);
+          const top = y(Math.max(from, from + v));
+          const bottom = y(Math.min(from, from + v));
+          ctx.fillStyle = colorAt(ds.backgroundColor, i, palette[d % palette.length]);
+          ctx.fillRect(x, top, barWidth, bottom - top);
+          if (ds.borderWidth && ds.borderColor) {
+            ctx.strokeStyle = colorAt(ds.borderColor, i, ctx.fillStyle);
+            ctx.lineWidth = ds.borderWidth;
+            ctx.strokeRect(x + 0.5, top + 0.5, barWidth - 1, Math.max(bottom - top - 1, 0));
+            ctx.lineWidth = 1;
+          }
+        });
+      }
+    } else {
+      datasets.forEach(function(ds, d) {
+        const color = colorAt(ds.borderColor, 0, palette[d % palette.length]);
+        const points = [];
+        for (let i = 0; i < n; i++) points.push({x: plot.left + slot * (i + 0.5), y: y(value(ds, i))});
+        if (!points.length) return;
+
+        const trace = function() {
+          ctx.beginPath();
+          ctx.moveTo(points[0].x, points[0].y);
+          for (let i = 1; i < points.length; i++) {
+            if (ds.tension) {
+              // curve through the midpoints, the data points act as control points
+              ctx.quadraticCurveTo(points[i - 1].x, points[i - 1].y, (points[i - 1].x + points[i].x) / 2, (points[i - 1].y + points[i].y) / 2);
+            } else {
+              ctx.lineTo(points[i].x, points[i].y);
+            }
+          }
+          ctx.lineTo(points[points.length - 1].x, points[points.length - 1].y);
+        };
+        if (ds.fill) {
+          trace();
+          ctx.lineTo(points[points.length - 1].x, y(Math.max(lo, 0)));
+          ctx.lineTo(points[0].x, y(Math.max(lo, 0)));
+          ctx.closePath();
+          ctx.save();
+          ctx.fillStyle = colorAt(ds.backgroundColor, 0, color);
+          ctx.globalAlpha = 0.3;
+          ctx.fill();
+          ctx.restore();
+        }
+        trace();
+        ctx.strokeStyle = color;
+        ctx.lineWidth = ds.borderWidth || 2;
+        ctx.stroke();
+        ctx.lineWidth = 1;
+
+        const radius = ds.pointRadius === undefined ? 3 : ds.pointRadius;
+        points.forEach(function(p, i) {
+          const r = i === index ? Math.max(radius, 3) + 1 : radius;
+          if (!r) return;
+          ctx.fillStyle = color;
+          ctx.beginPath();
+          ctx.arc(p.x, p.y, r, 0, Math.PI * 2);
+          ctx.fill();
+        });
+      });
+    }
+
+    if (index < 0 || index >= n) return null;
+    return {
+      title: String(labels[index]),
+      lines: datasets.map(function(ds, d) {
+        return this.tooltipLabel(ds, d, index, {x: index, y: value(ds, index)});
+      }, this)
+    };
+  };
+
+  MiniChart.prototype.drawPie = function(area) {
+    const ctx = this.ctx;
+    const ds = (this.data.datasets || [])[0];
+    if (!ds) return null;
+    const labels = this.data.labels || [];
+    const cx = (area.left + area.right) / 2;
+    const cy = (area.top + area.bottom) / 2;
+    const radius = Math.max(Math.min(area.right - area.left, area.bottom - area.top) / 2 - 4, 0);
+    const total = ds.data.reduce(function(sum, v, i) { return sum + Math.max(value(ds, i), 0); }, 0);
+    if (!total || !radius) return null;
+
+    let hovered = -1;
+    let hoverAngle = null;
+    if (this.hover) {
+      const dx = this.hover.x - cx;
+      const dy = this.hover.y - cy;
+      if (dx * dx + dy * dy <= radius * radius) {
+        hoverAngle = Math.atan2(dy, dx) + Math.PI / 2;
+        if (hoverAngle < 0) hoverAngle += Math.PI * 2;
+      }
+    }
+
+    let start = 0;
+    ds.data.forEach(function(v, i) {
+      const sweep = Math.max(value(ds, i), 0) / total * Math.PI * 2;
+      if (hoverAngle !== null && hoverAngle >= start && hoverAngle < start + sweep) hovered = i;
+      ctx.beginPath();
+      ctx.moveTo(cx, cy);
+      ctx.arc(cx, cy, i === hovered ? radius : radius - 3, start - Math.PI / 2, start + sweep - Math.PI / 2);
+      ctx.closePath();
+      ctx.fillStyle = colorAt(ds.backgroundColor, i, palette[i % palette.length]);
+      ctx.fill();
+      ctx.strokeStyle = '#fff';
+      ctx.stroke();
+      start += sweep;
+    });
+
+    if (hovered < 0) return null;
+    return {
+      title: '',
+      lines: [this.tooltipLabel(ds, 0, hovered, value(ds, hovered), String(labels[hovered]))]
+    };
+  };
+
+  // tooltipLabel — text of one tooltip line, from plugins.tooltip.callbacks.label when set
+  MiniChart.prototype.tooltipLabel = function(ds, datasetIndex, dataIndex, parsed, label) {
+    const labels = this.data.labels || [];
+    const v = typeof parsed === 'number' ? parsed : parsed.y;
+    const callback = option(this.options, 'plugins.tooltip.callbacks.label', null);
+    if (callback) {
+      return String(callback({
+        chart: this,
+        dataset: ds,
+        datasetIndex: datasetIndex,
+        dataIndex: dataIndex,
+        label: labels[dataIndex],
+        raw: ds.data[dataIndex],
+        parsed: parsed,
+        formattedValue: format(v)
+      }));
+    }
+    return (label || ds.label || '') + ': ' + format(v);
+  };
+
+  MiniChart.prototype.drawTooltip = function(tip) {
+    const ctx = this.ctx;
+    const lines = (tip.title ? [tip.title] : []).concat(tip.lines);
+    const width = Math.min(Math.max.apply(null, lines.map(function(l) { return ctx.measureText(l).width; })) + 12, this.width - 4);
+    const height = lines.length * 16 + 8;
+    let x = this.hover.x + 12;
+    let y = this.hover.y + 12;
+    if (x + width > this.width) x = Math.max(this.hover.x - width - 12, 2);
+    if (y + height > this.height) y = Math.max(this.height - height - 2, 2);
+
+    ctx.fillStyle = 'rgba(0, 0, 0, 0.8)';
+    ctx.fillRect(x, y, width, height);
+    ctx.fillStyle = '#fff';
+    ctx.textAlign = 'left';
+    lines.forEach(function(l, i) {
+      ctx.font = i === 0 && tip.title ? boldFont : font;
+      ctx.fillText(truncate(ctx, l, width - 12), x + 6, y + 12 + i * 16);
+    });
+    ctx.font = font;
+  };
+
+  window.MiniChart = MiniChart;
+})();
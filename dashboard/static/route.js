@@ -0,0 +1,229 @@
+const basePath = document.body.dataset.base;
+const params = new URLSearchParams(location.search);
+const method = params.get('method') || '';
+const path = params.get('path') || '';
+const scope = params.get('scope') || 'inbound';
+const charts = {};
+
+const classColors = {
+  '1xx': '#888', '2xx': '#2ecc40', '3xx': '#3498db', '4xx': '#f1c40f', '5xx': '#e74c3c', 'none': '#8e44ad'
+};
+
+document.getElementById('title').textContent = method + ' ' + path + (scope === 'outbound' ? ' (outbound)' : '');
+document.title = 'goapimon ' + method + ' ' + path;
+
+// apiPath — API URL of this route, path segments are escaped one by one
+function apiPath(endpoint) {
+  const segments = path.replace(/^\//, '').split('/').map(encodeURIComponent).join('/');
+  return basePath + 'api/v1/' + endpoint + '/' + encodeURIComponent(method) + '/' + segments + '?scope=' + encodeURIComponent(scope);
+}
+
+// upsertChart updates an existing chart in place instead of rebuilding it
+function upsertChart(chart, ctx, config) {
+  if (!chart || chart.config.type !== config.type) {
+    if (chart) chart.destroy();
+    return new MiniChart(ctx, config);
+  }
+  chart.data.labels = config.data.labels;
+  chart.data.datasets.length = config.data.datasets.length;
+  config.data.datasets.forEach(function(ds, i) {
+    if (chart.data.datasets[i]) Object.assign(chart.data.datasets[i], ds);
+    else chart.data.datasets[i] = ds;
+  });
+  chart.update('none');
+  return chart;
+}
+
+function lineChart(id, title, labels, datasets, options) {
+  charts[id] = upsertChart(charts[id], document.getElementById(id).getContext('2d'), {
+    type: 'line',
+    data: {
+      labels: labels,
+      datasets: datasets.map(function(ds) {
+        return Object.assign({fill: false, pointRadius: 0, borderWidth: 1.5, tension: 0.2}, ds);
+      })
+    },
+    options: Object.assign({
+      responsive: true,
+      animation: false,
+      plugins: {title: {display: true, text: title}},
+      scales: {y: {beginAtZero: true}}
+    }, options || {})
+  });
+}
+
+function render(history) {
+  const points = history.points;
+  const labels = points.map(function(p) {
+    return new Date(p.time).toLocaleTimeString([], {hour: '2-digit', minute: '2-digit'});
+  });
+  const series = function(key) {
+    return points.map(function(p) { return p[key]; });
+  };
+
+  lineChart('rpsHistory', 'Requests per second', labels, [
+    {label: 'RPS', data: series('rps'), borderColor: '#1f777e'}
+  ]);
+  lineChart('latencyHistory', 'Latency (ms)', labels, [
+    {label: 'avg', data: series('avg'), borderColor: '#3498db'},
+    {label: 'p50', data: series('p50'), borderColor: '#2ecc40'},
+    {label: 'p95', data: series('p95'), borderColor: '#f39c12'},
+    {label: 'p99', data: series('p99'), borderColor: '#e74c3c'}
+  ]);
+  lineChart('errorHistory', 'Error rate %', labels, [
+    {label: 'errors %', data: series('error_rate'), borderColor: '#e74c3c'}
+  ], {scales: {y: {beginAtZero: true, suggestedMax: 5}}});
+
+  const classes = Object.keys(classColors).filter(function(c) {
+    return points.some(function(p) { return p.status[c]; });
+  });
+  charts.statusHistory = upsertChart(charts.statusHistory, document.getElementById('statusHistory').getContext('2d'), {
+    type: 'bar',
+    data: {
+      labels: labels,
+      datasets: classes.map(function(c) {
+        return {
+          label: c,
+          data: points.map(function(p) { return p.status[c] || 0; }),
+          backgroundColor: classColors[c]
+        };
+      })
+    },
+    options: {
+      responsive: true,
+      animation: false,
+      plugins: {title: {display: true, text: 'Status mix'}},
+      scales: {x: {stacked: true}, y: {stacked: true, beginAtZero: true}}
+    }
+  });
+
+  document.getElementById('note').textContent = 'Last ' + Math.round(history.retention / 60) + ' min, one point per ' + history.interval + 's';
+}
+
+// getJSON fetches an API endpoint, rejecting with the server's error message
+function getJSON(url) {
+  return fetch(url).then(function(res) {
+    return res.json().then(function(body) {
+      if (!res.ok) throw new Error(body.error || res.statusText);
+      return body;
+    });
+  });
+}
+
+function escapeHTML(s) {
+  return String(s).replace(/[&<>"']/g, function(c) {
+    return {'&': '&amp;', '<': '&lt;', '>': '&gt;', '"': '&quot;', "'": '&#39;'}[c];
+  });
+}
+
+function statusText(s) {
+  if (s.transport_error) return 'no response: ' + s.transport_error;
+  if (s.grpc_code) return s.grpc_code;
+  return String(s.status);
+}
+
+function renderSamples(id, samples) {
+  if (!samples.length) {
+    document.getElementById(id).innerHTML = '<div class="empty">None recorded.</div>';
+    return;
+  }
+  let html = '<table><thead><tr><th>Time</th><th>Duration ms</th><th>Status</th><th>Client</th><th>Trace ID</th></tr></thead><tbody>';
+  samples.forEach(function(s) {
+    const failed = s.transport_error || s.status >= 400;
+    html += '<tr' + (failed ? ' class="error"' : '') + '><td>' + escapeHTML(new Date(s.time).toLocaleString()) + '</td><td>' + s.duration.toFixed(2) + '</td><td>' + escapeHTML(statusText(s)) + '</td><td class="mono">' + escapeHTML(s.client || '') + '</td><td class="mono">' + escapeHTML(s.trace_id || '') + '</td></tr>';
+  });
+  html += '</tbody></table>';
+  document.getElementById(id).innerHTML = html;
+}
+
+function renderSummary(detail, windows) {
+  let html = '<table><thead><tr><th>Window</th><th>Count</th><th>Error rate %</th><th>RPS</th><th>Avg ms</th><th>p50 ms</th><th>p95 ms</th><th>p99 ms</th><th>Max ms</th></tr></thead><tbody>';
+  windows.forEach(function(w) {
+    const row = detail.windows[w.name];
+    if (!row) {
+      html += '<tr><td>' + escapeHTML(w.name) + '</td><td colspan="8" class="muted">no requests</td></tr>';
+      return;
+    }
+    html += '<tr' + (row.HasError ? ' class="error"' : '') + '><td>' + escapeHTML(w.name) + '</td><td>' + row.Count + '</td><td>' + row.ErrorRate.toFixed(2) + '</td><td>' + (row.Throughput === -1 ? 'N/A' : row.Throughput.toFixed(2)) + '</td><td>' + row.Avg.toFixed(2) + '</td><td>' + row.P50.toFixed(2) + '</td><td>' + row.P95.toFixed(2) + '</td><td>' + row.P99.toFixed(2) + '</td><td>' + (row.Max === -1 ? 'N/A' : row.Max.toFixed(2)) + '</td></tr>';
+  });
+  html += '</tbody></table>';
+  document.getElementById('summary').innerHTML = html;
+}
+
+function loadLatency() {
+  const win = document.getElementById('histWindow').value;
+  getJSON(apiPath('latency') + '&window=' + encodeURIComponent(win)).then(function(h) {
+    const labels = h.bounds.map(function(b) { return '≤ ' + b + ' ms'; });
+    labels.push('> ' + h.bounds[h.bounds.length - 1] + ' ms');
+    charts.latencyDistribution = upsertChart(charts.latencyDistribution, document.getElementById('latencyDistribution').getContext('2d'), {
+      type: 'bar',
+      data: {
+        labels: labels,
+        datasets: [{label: 'requests', data: h.counts, backgroundColor: '#1f777e'}]
+      },
+      options: {
+        responsive: true,
+        animation: false,
+        plugins: {title: {display: true, text: 'Latency distribution (' + h.window + ')'}, legend: {display: false}},
+        scales: {y: {beginAtZero: true}}
+      }
+    });
+  });
+}
+
+function loadDetails() {
+  Promise.all([getJSON(basePath + 'api/v1/windows'), getJSON(apiPath('routes'))]).then(function(res) {
+    const windows = res[0];
+    renderSummary(res[1], windows);
+    const select = document.getElementById('histWindow');
+    if (!select.options.length) {
+      select.innerHTML = windows.map(function(w) {
+        return '<option value="' + escapeHTML(w.name) + '">' + escapeHTML(w.name) + '</option>';
+      }).join('');
+    }
+    loadLatency();
+  }).catch(function(err) {
+    document.getElementById('summary').innerHTML = '<div class="empty">' + escapeHTML(err.message) + '</div>';
+  });
+  getJSON(apiPath('requests')).then(function(r) {
+    renderSamples('slowest', r.slowest);
+    renderSamples('errors', r.errors);
+  }).catch(function(err) {
+    document.getElementById('slowest').innerHTML = '<div class="empty">' + escapeHTML(err.message) + '</div>';
+    document.getElementById('errors').innerHTML = '';
+  });
+}
+
+function load() {
+  loadDetails();
+  getJSON(apiPath('history')).then(function(body) {
+    render(body);
+    setTimeout(load, body.interval * 1000);
+  }).catch(function(err) {
+    document.getElementById('note').textContent = 'History unavailable: ' + err.message;
+  });
+}
+
+function updateLogo() {
+  const isDark = document.body.classList.contains('dark');
+  const logo = document.getElementById('logo-img');
+  logo.src = basePath + (isDark ? 'static/goapimon_white.png' : 'static/goapimon_green.png');
+}
+
+function toggleTheme() {
+  const dark = document.body.classList.toggle('dark');
+  document.getElementById('theme-toggle').textContent = dark ? 'Light' : 'Dark';
+  localStorage.setItem('goapimon-theme', dark ? 'dark' : '');
+  updateLogo();
+}
+
+if (localStorage.getItem('goapimon-theme') === 'dark') {
+  document.body.classList.add('dark');
+  document.getElementById('theme-toggle').textContent = 'Light';
+  updateLogo();
+}
+
+document.getElementById('theme-toggle').onclick = toggleTheme;
+document.getElementById('histWindow').onchange = loadLatency;
+
+load();
@@ -0,0 +1,232 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultPushInterval — how often the live dashboard receives new stats
+const DefaultPushInterval = 5 * time.Second
+
+// RouteRef — identifies a row removed from a window
+type RouteRef struct {
+	Method string `json:"Method"`
+	Path   string `json:"Path"`
+}
+
+// WindowDelta — rows of one window that changed or disappeared since the previous event
+type WindowDelta struct {
+	Set []Row      `json:"set,omitempty"`
+	Del []RouteRef `json:"del,omitempty"`
+}
+
+// frame — rows of every scope and window computed at one tick and shared by all streams.
+// encoded holds each row as JSON, indexed by scope, window and route, to find changed rows.
+// Frames are never modified once published.
+type frame struct {
+	scopes  map[string]map[string][]Row
+	encoded map[string]map[string]map[RouteRef][]byte
+}
+
+// hub computes one frame per PushInterval while at least one stream is connected,
+// so the digests are merged once per tick rather than once per viewer.
+type hub struct {
+	mu      sync.Mutex
+	streams int
+	latest  *frame
+	next    chan struct{} // closed when latest is replaced
+	stop    chan struct{} // closed when the last stream leaves
+}
+
+// stream pushes Server-Sent Events: a "snapshot" with every row on connect,
+// then a "delta" per PushInterval with changed rows only.
+// Rows are keyed by Method + Path, the page merges deltas into its data.
+func (d *Dashboard) stream(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	// The stream outlives any server write timeout
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	interval := d.pushInterval()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no") // disable proxy buffering (nginx)
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", interval.Milliseconds())
+	sent, next := d.hub.subscribe(d)
+	defer d.hub.unsubscribe()
+	if err := writeEvent(w, "snapshot", sent.scopes); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-next:
+		}
+
+		// A slow client skips frames, its delta is taken against the last frame it got
+		var f *frame
+		f, next = d.hub.current()
+		delta := sent.delta(f)
+		sent = f
+
+		var err error
+		if len(delta) > 0 {
+			err = writeEvent(w, "delta", delta)
+		} else {
+			// keeps proxies from closing an idle connection
+			_, err = io.WriteString(w, ": ping\n\n")
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (d *Dashboard) pushInterval() time.Duration {
+	if d.PushInterval <= 0 {
+		return DefaultPushInterval
+	}
+	return d.PushInterval
+}
+
+// subscribe returns the latest frame and a channel closed when the next one is ready.
+// The first stream computes a fresh frame and starts the producer.
+func (h *hub) subscribe(d *Dashboard) (*frame, <-chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.streams == 0 {
+		h.latest = d.newFrame()
+		h.next = make(chan struct{})
+		h.stop = make(chan struct{})
+		go h.run(d, d.pushInterval(), h.stop)
+	}
+	h.streams++
+	return h.latest, h.next
+}
+
+// unsubscribe stops the producer when the last stream leaves.
+func (h *hub) unsubscribe() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.streams--
+	if h.streams == 0 {
+		close(h.stop)
+		h.latest = nil
+	}
+}
+
+func (h *hub) current() (*frame, <-chan struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.latest, h.next
+}
+
+// run publishes a frame per interval until stop is closed.
+func (h *hub) run(d *Dashboard, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		// Computed without the lock, streams keep reading the previous frame meanwhile
+		f := d.newFrame()
+
+		h.mu.Lock()
+		select {
+		case <-stop:
+			// every stream left while computing, a new producer may already run
+			h.mu.Unlock()
+			return
+		default:
+		}
+		h.latest = f
+		close(h.next)
+		h.next = make(chan struct{})
+		h.mu.Unlock()
+	}
+}
+
+// newFrame computes rows of served routes and outbound calls and encodes each row once.
+func (d *Dashboard) newFrame() *frame {
+	f := &frame{
+		scopes:  d.scopeData(),
+		encoded: make(map[string]map[string]map[RouteRef][]byte),
+	}
+	for scope, windows := range f.scopes {
+		f.encoded[scope] = make(map[string]map[RouteRef][]byte, len(windows))
+		for window, rows := range windows {
+			enc := make(map[RouteRef][]byte, len(rows))
+			for _, row := range rows {
+				enc[RouteRef{Method: row.Method, Path: row.Path}], _ = json.Marshal(row)
+			}
+			f.encoded[scope][window] = enc
+		}
+	}
+	return f
+}
+
+// scopeData computes rows of served routes and outbound calls.
+func (d *Dashboard) scopeData() map[string]map[string][]Row {
+	return map[string]map[string][]Row{
+		"inbound":  d.calcData(d.Store.Snapshot()),
+		"outbound": d.calcData(d.Store.OutboundSnapshot()),
+	}
+}
+
+// delta lists rows of next that are new or changed since f and rows that disappeared.
+func (f *frame) delta(next *frame) map[string]map[string]*WindowDelta {
+	out := make(map[string]map[string]*WindowDelta)
+	for scope, windows := range next.scopes {
+		for window, rows := range windows {
+			prev, cur := f.encoded[scope][window], next.encoded[scope][window]
+			var wd WindowDelta
+			for _, row := range rows {
+				ref := RouteRef{Method: row.Method, Path: row.Path}
+				if !bytes.Equal(prev[ref], cur[ref]) {
+					wd.Set = append(wd.Set, row)
+				}
+			}
+			for ref := range prev {
+				if _, ok := cur[ref]; !ok {
+					wd.Del = append(wd.Del, ref)
+				}
+			}
+
+			if len(wd.Set) == 0 && len(wd.Del) == 0 {
+				continue
+			}
+			if out[scope] == nil {
+				out[scope] = make(map[string]*WindowDelta)
+			}
+			out[scope][window] = &wd
+		}
+	}
+	return out
+}
+
+func writeEvent(w io.Writer, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
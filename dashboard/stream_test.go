@@ -0,0 +1,118 @@
+package dashboard
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+)
+
+// readEvent returns the next event and its data, skipping comments.
+func readEvent(t *testing.T, sc *bufio.Scanner) (event, data string) {
+	t.Helper()
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	t.Fatalf("stream ended: %v", sc.Err())
+	return "", ""
+}
+
+func TestStreamSharesFrames(t *testing.T) {
+	d := testDashboard(false)
+	d.PushInterval = 20 * time.Millisecond
+	srv := httptest.NewServer(d.Handler())
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var streams []*bufio.Scanner
+	for range 2 {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+base+"api/v1/stream", nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		sc := bufio.NewScanner(resp.Body)
+		sc.Buffer(nil, 1<<20)
+		if event, data := readEvent(t, sc); event != "snapshot" || !strings.Contains(data, `"/users/:id"`) {
+			t.Fatalf("first event %s %s, want a snapshot with every route", event, data)
+		}
+		streams = append(streams, sc)
+	}
+
+	d.hub.mu.Lock()
+	if d.hub.streams != 2 {
+		t.Errorf("%d streams registered, want 2", d.hub.streams)
+	}
+	d.hub.mu.Unlock()
+
+	d.Store.Record(model.RequestRecord{Timestamp: time.Now(), Duration: time.Millisecond, Status: 201, Method: "PUT", Path: "/new"})
+	for i, sc := range streams {
+		for {
+			event, data := readEvent(t, sc)
+			if event != "delta" {
+				t.Fatalf("stream %d: event %q", i, event)
+			}
+			var delta map[string]map[string]*WindowDelta
+			if err := json.Unmarshal([]byte(data), &delta); err != nil {
+				t.Fatal(err)
+			}
+			if wd := delta["inbound"]["1m"]; wd != nil && len(wd.Set) == 1 && wd.Set[0].Path == "/new" {
+				break
+			}
+		}
+	}
+
+	// The producer stops with the last stream
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d.hub.mu.Lock()
+		streams, latest := d.hub.streams, d.hub.latest
+		d.hub.mu.Unlock()
+		if streams == 0 && latest == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d streams still registered after disconnecting", streams)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestFrameDelta(t *testing.T) {
+	d := testDashboard(false)
+	first := d.newFrame()
+	// Lifetime rates of the total window move with the clock, windowed rows don't
+	if wd := first.delta(d.newFrame())["inbound"]["1m"]; wd != nil {
+		t.Errorf("delta without changes: %+v", wd)
+	}
+
+	d.Store.Reset()
+	d.Store.Record(model.RequestRecord{Timestamp: time.Now(), Duration: time.Millisecond, Status: 503, Method: "GET", Path: "/health"})
+	delta := first.delta(d.newFrame())
+	wd := delta["inbound"]["1m"]
+	if wd == nil || len(wd.Set) != 1 || len(wd.Del) != 3 {
+		t.Fatalf("1m delta %+v, want /health changed and 3 routes removed", wd)
+	}
+	if wd.Set[0].Path != "/health" {
+		t.Errorf("changed %q, want /health", wd.Set[0].Path)
+	}
+	if out := delta["outbound"]["1m"]; out == nil || len(out.Del) != 1 {
+		t.Errorf("outbound delta %+v, want the call removed", out)
+	}
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"github.com/aurieli333/goapimon-deploy"
+	"github.com/aurieli333/goapimon"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,7 +16,7 @@ func main() {
 	goapimon.PrometheusEnable("/metrics")
 
 	// Use goapimon middleware for gin
-	r.Use(goapimon.GinMiddleware(goapimon.Monitor))
+	r.Use(goapimon.MiddlewareGin(goapimon.Monitor))
 
 	// Your API endpoint
 	r.GET("/hello", func(c *gin.Context) {
@@ -24,8 +24,7 @@ func main() {
 	})
 
 	// Creating handlers
-	r.Any("/__goapimon/*any", gin.WrapF(goapimon.DashboardHandler))
-	r.GET("/metrics", gin.WrapF(goapimon.PrometheusHandler))
+	goapimon.MountGin(r)
 
 	r.Run(":8080")
 }
@@ -26,9 +26,8 @@ func main() {
 	// Turn on Prometheus with metrics path (optional)
 	goapimon.PrometheusEnable("/metrics")
 
-	// Add handlers
-	mux.HandleFunc("/__goapimon/", goapimon.DashboardHandler)
-	mux.HandleFunc("/metrics", goapimon.PrometheusHandler)
+	// Add dashboard and metrics handlers
+	goapimon.Mount(mux)
 
 	logged := goapimon.MiddlewareNetHTTP(goapimon.Monitor, mux)
 
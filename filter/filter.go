@@ -0,0 +1,139 @@
+// Package filter decides which requests are monitored,
+// e.g. to leave out health checks, static assets or OPTIONS requests.
+package filter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Kind — how a Rule compares its pattern with the request path
+type Kind int
+
+const (
+	KindExact  Kind = iota // path equals the pattern
+	KindPrefix             // path starts with the pattern
+	KindGlob               // path.Match syntax, * does not cross /
+	KindAny                // every path, used to filter by method only
+)
+
+// Rule — matches requests by path and, optionally, method
+type Rule struct {
+	Kind    Kind
+	Pattern string
+	Methods []string // empty matches every method
+}
+
+// Exact — Rule matching path exactly, e.g. Exact("/healthz")
+func Exact(path string, methods ...string) Rule {
+	return Rule{Kind: KindExact, Pattern: path, Methods: methods}
+}
+
+// Prefix — Rule matching paths starting with prefix, e.g. Prefix("/static/")
+func Prefix(prefix string, methods ...string) Rule {
+	return Rule{Kind: KindPrefix, Pattern: prefix, Methods: methods}
+}
+
+// Glob — Rule matching paths with path.Match, e.g. Glob("/assets/*.js")
+func Glob(pattern string, methods ...string) Rule {
+	return Rule{Kind: KindGlob, Pattern: pattern, Methods: methods}
+}
+
+// Methods — Rule matching every path requested with one of methods, e.g. Methods("OPTIONS", "HEAD")
+func Methods(methods ...string) Rule {
+	return Rule{Kind: KindAny, Methods: methods}
+}
+
+// Validate reports malformed glob patterns and unknown kinds.
+func (r Rule) Validate() error {
+	switch r.Kind {
+	case KindExact, KindPrefix, KindAny:
+		return nil
+	case KindGlob:
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return fmt.Errorf("filter: glob %q: %w", r.Pattern, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("filter: unknown rule kind %d", r.Kind)
+	}
+}
+
+// Match reports whether the request method + path matches the rule.
+func (r Rule) Match(method, p string) bool {
+	if len(r.Methods) > 0 && !hasMethod(r.Methods, method) {
+		return false
+	}
+	switch r.Kind {
+	case KindExact:
+		return p == r.Pattern
+	case KindPrefix:
+		return strings.HasPrefix(p, r.Pattern)
+	case KindGlob:
+		ok, _ := path.Match(r.Pattern, p)
+		return ok
+	case KindAny:
+		return true
+	}
+	return false
+}
+
+func hasMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// Under — rules matching base and everything below it, e.g. the dashboard
+func Under(base string) []Rule {
+	base = strings.TrimSuffix(base, "/")
+	return []Rule{Exact(base), Prefix(base + "/")}
+}
+
+// Filter — include and exclude lists, exclusion wins
+type Filter struct {
+	Include []Rule // when not empty only matching requests are monitored
+	Exclude []Rule
+}
+
+// Monitored reports whether a request passes the filter, a nil Filter passes everything.
+func (f *Filter) Monitored(method, path string) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include, method, path) {
+		return false
+	}
+	return !matchAny(f.Exclude, method, path)
+}
+
+// Validate checks every rule of the filter.
+func (f *Filter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	for _, r := range f.Include {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, r := range f.Exclude {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func matchAny(rules []Rule, method, path string) bool {
+	for _, r := range rules {
+		if r.Match(method, path) {
+			return true
+		}
+	}
+	return false
+}
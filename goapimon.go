@@ -1,54 +1,220 @@
 package goapimon
 
 import (
-	"sync"
-	"time"
+	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/aurieli333/goapimon/adapters"
+	"github.com/aurieli333/goapimon/auth"
+	"github.com/aurieli333/goapimon/config"
 	"github.com/aurieli333/goapimon/dashboard"
+	"github.com/aurieli333/goapimon/filter"
 	"github.com/aurieli333/goapimon/model"
 	"github.com/aurieli333/goapimon/monitor"
 	"github.com/aurieli333/goapimon/prometheus"
+	"github.com/aurieli333/goapimon/store"
+
+	"github.com/gin-gonic/gin"
 )
 
-// Global mutex shared across all statistics consumers
-var mu = &sync.Mutex{}
+// Instance — independent goapimon instance with its own stats, dashboard and exporter
+type Instance struct {
+	Store      *store.Store
+	Monitor    *monitor.Monitor
+	Dashboard  *dashboard.Dashboard
+	Prometheus *prometheus.Prometheus
+
+	windows []model.Window
+}
+
+// New — creates an independent instance, so several servers in one process
+// (or several tests) don't share statistics
+func New(opts Options) (*Instance, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	windows := opts.Windows
+	guard, err := auth.New(opts.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("goapimon: %w", err)
+	}
+
+	s := store.NewStore(store.Options{
+		Retention:        opts.Retention,
+		BucketWidth:      opts.BucketWidth,
+		HistogramBuckets: opts.HistogramBuckets,
+		MaxRoutes:        max(opts.MaxRoutesPerMethod, 0),
+		HistoryRetention: max(opts.HistoryRetention, 0),
+		HistoryInterval:  opts.HistoryInterval,
+		Samples:          max(opts.RequestSamples, 0),
+	})
+
+	prom := prometheus.NewPrometheus(s, windows)
+	prom.Format = opts.PrometheusFormat
+	prom.Auth = guard
+
+	mon := monitor.NewMonitor(s)
+	mon.Normalizer = opts.Normalizer
+	mon.Filter = opts.filter()
+	mon.ExcludeInternal(filter.Under(opts.DashboardPath)...)
+	mon.ExcludeInternal(filter.Exact(config.MetricsPath))
+
+	dash := dashboard.NewDashboard(s, windows)
+	dash.BasePath = opts.DashboardPath
+	dash.PushInterval = opts.DashboardPushInterval
+	dash.Auth = guard
+
+	return &Instance{
+		Store:      s,
+		Monitor:    mon,
+		Dashboard:  dash,
+		Prometheus: prom,
+		windows:    windows,
+	}, nil
+}
+
+// Reset — drops all statistics collected by this instance
+func (i *Instance) Reset() {
+	i.Store.Reset()
+}
+
+// Windows — returns the windows configured for this instance
+func (i *Instance) Windows() []model.Window {
+	return append([]model.Window(nil), i.windows...)
+}
 
-// Define default time windows for analysis
-var windows = []model.Window{
-	{Name: "1m", Length: 1 * time.Minute},
-	{Name: "2m", Length: 2 * time.Minute},
-	{Name: "5m", Length: 5 * time.Minute},
-	// Add more windows here if needed
+// DashboardHandler — HTTP handler serving the dashboard UI of this instance
+func (i *Instance) DashboardHandler() http.HandlerFunc {
+	return i.Dashboard.Handler()
 }
 
-// Global stats shared between monitor, dashboard, and prometheus modules
-var stats = make(map[string]map[string]*model.RouteStats)
+// PrometheusHandler — HTTP handler exposing Prometheus metrics of this instance
+func (i *Instance) PrometheusHandler() http.HandlerFunc {
+	return i.Prometheus.Handler()
+}
+
+// DashboardEnable — enables the dashboard of this instance
+func (i *Instance) DashboardEnable() {
+	i.Dashboard.Enable()
+}
+
+// PrometheusEnable — enables Prometheus metrics of this instance and sets its endpoint path,
+// which is excluded from monitoring
+func (i *Instance) PrometheusEnable(path string) {
+	i.Prometheus.Enable(path)
+	if path != "" {
+		i.Monitor.ExcludeInternal(filter.Exact(path))
+	}
+}
+
+// Mux — router accepting net/http patterns, such as *http.ServeMux or *adapters.ServeMux
+type Mux interface {
+	Handle(pattern string, handler http.Handler)
+}
+
+// Mount — registers the dashboard under its base path and the Prometheus handler
+// under its path (/metrics until PrometheusEnable sets another one).
+// Both still answer 404 until they are enabled.
+func (i *Instance) Mount(mux Mux) {
+	mux.Handle(i.Dashboard.BasePath, i.DashboardHandler())
+	mux.Handle(i.metricsPath(), i.PrometheusHandler())
+}
+
+// GinRouter — a *gin.Engine or a *gin.RouterGroup
+type GinRouter interface {
+	gin.IRoutes
+	BasePath() string
+}
+
+// MountGin — Mount for Gin routers and route groups. On a group the dashboard and
+// metrics paths are prefixed with the group's path, e.g. /admin/__goapimon/ for
+// r.Group("/admin"), so mount the dashboard once.
+func (i *Instance) MountGin(r GinRouter) {
+	dashPath, metricsPath := i.Dashboard.BasePath, i.metricsPath()
+	if prefix := strings.TrimSuffix(r.BasePath(), "/"); prefix != "" {
+		// The dashboard builds its links from the full path
+		i.Dashboard.BasePath = prefix + dashPath
+		i.Monitor.ExcludeInternal(filter.Under(i.Dashboard.BasePath)...)
+		i.Monitor.ExcludeInternal(filter.Exact(prefix + metricsPath))
+	}
+	r.Any(dashPath+"*any", gin.WrapF(i.DashboardHandler()))
+	r.GET(metricsPath, gin.WrapF(i.PrometheusHandler()))
+}
+
+func (i *Instance) metricsPath() string {
+	if i.Prometheus.Path != "" {
+		return i.Prometheus.Path
+	}
+	return config.MetricsPath
+}
+
+// MiddlewareNetHTTP — net/http middleware recording into this instance
+func (i *Instance) MiddlewareNetHTTP(next http.Handler) http.Handler {
+	return adapters.MiddlewareNetHTTP(i.Monitor, next)
+}
+
+// MiddlewareGin — Gin middleware recording into this instance
+func (i *Instance) MiddlewareGin() gin.HandlerFunc {
+	return adapters.MiddlewareGin(i.Monitor)
+}
+
+// RoundTripper — http.RoundTripper recording outbound calls into this instance,
+// a nil next uses http.DefaultTransport
+func (i *Instance) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	return adapters.RoundTripper(i.Monitor, next)
+}
+
+func mustNew(opts Options) *Instance {
+	i, err := New(opts)
+	if err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// Default — instance behind the package-level API below
+var Default = mustNew(Options{})
 
 // Monitor — shared monitoring instance
-var Monitor = monitor.NewMonitor(stats)
+var Monitor = Default.Monitor
 
 // Dashboard — shared dashboard instance
-var Dashboard = dashboard.NewDashboard(mu, windows, stats)
+var Dashboard = Default.Dashboard
 
 // Prometheus — shared Prometheus metrics instance
-var Prometheus = prometheus.NewPrometheus(mu, windows, stats)
+var Prometheus = Default.Prometheus
 
 // DashboardHandler — public HTTP handler for serving the dashboard UI
-var DashboardHandler = Dashboard.Handler()
+var DashboardHandler = Default.DashboardHandler()
 
 // PrometheusHandler — public HTTP handler for exposing Prometheus metrics
-var PrometheusHandler = Prometheus.Handler()
+var PrometheusHandler = Default.PrometheusHandler()
 
 // DashboardEnable — enables the dashboard at runtime
 func DashboardEnable() {
-	Dashboard.Enable()
+	Default.DashboardEnable()
 }
 
 // PrometheusEnable — enables Prometheus metrics and sets its endpoint path
 func PrometheusEnable(path string) {
-	Prometheus.Enable(path)
+	Default.PrometheusEnable(path)
+}
+
+// Mount — registers the dashboard and Prometheus handlers on a net/http mux
+func Mount(mux Mux) {
+	Default.Mount(mux)
+}
+
+// MountGin — registers the dashboard and Prometheus handlers on a Gin router
+func MountGin(r GinRouter) {
+	Default.MountGin(r)
 }
 
 var MiddlewareGin = adapters.MiddlewareGin
 var MiddlewareNetHTTP = adapters.MiddlewareNetHTTP
+var RoundTripper = adapters.RoundTripper
+
+// WithTraceID — attaches a trace ID to the request context, it is exported as an exemplar
+var WithTraceID = adapters.WithTraceID
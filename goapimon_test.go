@@ -0,0 +1,112 @@
+package goapimon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aurieli333/goapimon/auth"
+	"github.com/aurieli333/goapimon/config"
+	"github.com/aurieli333/goapimon/filter"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMountGinGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mon, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mon.DashboardEnable()
+	mon.PrometheusEnable(config.MetricsPath)
+
+	r := gin.New()
+	r.Use(mon.MiddlewareGin())
+	mon.MountGin(r.Group("/admin"))
+
+	dash := "/admin" + config.DashboardPath
+	for _, path := range []string{dash, dash + "route", dash + "static/dashboard.js", "/admin" + config.MetricsPath} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: %d", path, w.Code)
+		}
+		if path == dash && !strings.Contains(w.Body.String(), `data-base="`+dash+`"`) {
+			t.Errorf("dashboard links do not start with %s", dash)
+		}
+	}
+
+	if routes := mon.Store.Snapshot(); len(routes) != 0 {
+		t.Errorf("dashboard and metrics requests recorded: %v", routes)
+	}
+}
+
+func TestAuthCoversEveryEndpoint(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		config auth.Config
+		want   int
+	}{
+		{"bearer", auth.Config{BearerTokens: []string{"secret"}}, http.StatusUnauthorized},
+		{"authorize", auth.Config{Authorize: func(*http.Request) bool { return false }}, http.StatusForbidden},
+		{"allowlist", auth.Config{AllowIPs: []string{"10.0.0.0/8"}}, http.StatusForbidden},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mon, err := New(Options{Auth: tt.config})
+			if err != nil {
+				t.Fatal(err)
+			}
+			mon.DashboardEnable()
+			mon.PrometheusEnable(config.MetricsPath)
+			mux := http.NewServeMux()
+			mon.Mount(mux)
+
+			dash := config.DashboardPath
+			for _, path := range []string{
+				dash,
+				dash + "route?method=GET&path=/a",
+				dash + "api/v1/windows",
+				dash + "api/v1/routes",
+				dash + "api/v1/stream",
+				dash + "export/csv",
+				dash + "static/dashboard.js",
+				config.MetricsPath,
+			} {
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				if w.Code != tt.want {
+					t.Errorf("GET %s: %d, want %d", path, w.Code, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoundTripperFilter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	mon, err := New(Options{Exclude: []filter.Rule{filter.Prefix(host + "/internal/")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: mon.RoundTripper(nil)}
+	for _, path := range []string{"/items/1", "/internal/ping"} {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s: %d", path, resp.StatusCode)
+		}
+	}
+
+	routes := mon.Store.OutboundSnapshot()
+	if len(routes) != 1 || len(routes[http.MethodGet]) != 1 || routes[http.MethodGet][host+"/items/:id"] == nil {
+		t.Errorf("outbound routes %v, want only %s/items/:id", routes, host)
+	}
+}
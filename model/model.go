@@ -1,31 +1,141 @@
 package model
 
 import (
-	"net/http"
+	"strings"
 	"time"
+
+	"github.com/influxdata/tdigest"
+)
+
+// Routes grouping requests that are not recorded under their own path
+const (
+	OtherRoute     = "__other__"     // new routes once the route limit of a method is reached
+	UnmatchedRoute = "__unmatched__" // requests no router route matched
 )
 
+// OtherMethod groups requests whose method is not a standard HTTP method
+const OtherMethod = "OTHER"
+
+// knownMethods — methods recorded under their own name, GRPC is used by the gRPC interceptors
+var knownMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true, "PATCH": true,
+	"DELETE": true, "CONNECT": true, "OPTIONS": true, "TRACE": true, "GRPC": true,
+}
+
+// GroupMethod returns method, or OtherMethod for methods a client made up,
+// so arbitrary request methods cannot add methods to the statistics.
+func GroupMethod(method string) string {
+	if knownMethods[method] {
+		return method
+	}
+	return OtherMethod
+}
+
 // Request data
 type RequestRecord struct {
 	Timestamp time.Time     // Время запроса
 	Duration  time.Duration // Длительность
 	Status    int           // HTTP статус
 	Method    string        // Метод (GET, POST...)
+	Path      string        // Route (normalized path)
+	TraceID   string        // Trace ID, empty when unknown
+	Client    string        // caller IP address, empty when unknown or for outbound calls
+	ReqBytes  int64         // Request body size
+	RespBytes int64         // Bytes written to the response body
+	LongLived bool          // Hijacked or streaming connection, excluded from latency stats
+
+	GRPCCode     string // gRPC status code name (OK, NotFound...), empty for HTTP requests
+	MsgsReceived int64  // messages received on a gRPC stream
+	MsgsSent     int64  // messages sent on a gRPC stream
+
+	Outbound       bool   // call made by this service to another host, Path starts with the host
+	TransportError string // outbound failure without a response (dns, timeout...), Status is 0
+}
+
+// Sample — a single request kept to show the slowest and failing requests of a route
+type Sample struct {
+	Timestamp      time.Time
+	Duration       time.Duration
+	Status         int
+	GRPCCode       string
+	TransportError string
+	Client         string
+	TraceID        string
+}
+
+// Exemplar — a single traced request attached to a histogram bucket
+type Exemplar struct {
+	TraceID   string
+	Value     float64 // seconds
+	Timestamp time.Time
+}
+
+// Bucket — requests of one route completed in [Start, End)
+type Bucket struct {
+	Start     time.Time
+	End       time.Time
+	Count     int
+	ErrCount  int
+	LongLived int // requests counted without latency
+	Status    map[int]int
+	Sum       time.Duration
+	Min       time.Duration
+	Max       time.Duration
+	Latency   tdigest.CentroidList // mergeable latency digest, ms
+
+	ReqBytes  int64
+	RespBytes int64
+	RespSize  tdigest.CentroidList // mergeable response size digest, bytes
+
+	GRPCStatus   map[string]int // gRPC code counts, nil for HTTP routes
+	MsgsReceived int64
+	MsgsSent     int64
+
+	TransportErrors map[string]int // outbound failures by kind, nil when none
+}
+
+// HistoryPoint — requests of one route completed in [Start, Start + history interval).
+// Points are kept much longer than buckets, so they hold no digests, only a few percentiles.
+type HistoryPoint struct {
+	Start     time.Time
+	Count     int
+	ErrCount  int
+	LongLived int    // requests counted without latency
+	Classes   [6]int // requests per status class: 1-5 for 1xx-5xx, 0 for calls without a response
+	Sum       time.Duration
+	Max       time.Duration
+	P50       float64 // ms
+	P95       float64 // ms
+	P99       float64 // ms
 }
 
 // Store only N minutes
 type RouteStats struct {
-	Recent []RequestRecord // Raw data for last interval
+	Buckets []Bucket // Time buckets for last interval, oldest first
 
 	// Aggregates
 	TotalCount      int
 	TotalErrorCount int
+	TotalLongLived  int // requests counted without latency
 	TotalStatus     map[int]int
 	TotalTime       time.Duration
+	TotalReqBytes   int64
+	TotalRespBytes  int64
 	TotalMin        time.Duration
 	TotalMax        time.Duration
+	TotalLatency    tdigest.CentroidList // lifetime latency digest, ms
+	TotalHistogram  []int                // lifetime counts per histogram bound, last one is +Inf
+	TotalExemplars  []Exemplar           // latest traced request per histogram bound
 	FirstSeen       time.Time
 	LastSeen        time.Time
+
+	// gRPC only
+	TotalGRPCStatus   map[string]int // code counts, nil for HTTP routes
+	TotalMsgsReceived int64          // stream messages received
+	TotalMsgsSent     int64          // stream messages sent
+
+	// Outbound only
+	TotalTransportErrors map[string]int // failures without a response by kind, nil when none
 }
 
 type Window struct {
@@ -33,13 +143,16 @@ type Window struct {
 	Length time.Duration
 }
 
-// statusRecorder — for storing status
-type StatusRecorder struct {
-	http.ResponseWriter
-	Status int
+// OutboundPath — route of an outbound call, the host followed by the normalized path
+func OutboundPath(host, path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return host + path
 }
 
-func (r *StatusRecorder) WriteHeader(code int) {
-	r.Status = code
-	r.ResponseWriter.WriteHeader(code)
+// SplitOutboundPath — splits a route built by OutboundPath into host and path
+func SplitOutboundPath(route string) (host, path string) {
+	host, path, _ = strings.Cut(route, "/")
+	return host, "/" + path
 }
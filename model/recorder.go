@@ -0,0 +1,272 @@
+package model
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+)
+
+// StatusRecorder — for storing status, response size and connection state.
+// Handlers get Writer(), which implements exactly those of http.Flusher, http.Hijacker,
+// http.Pusher and io.ReaderFrom that the wrapped writer implements, so feature checks
+// like w.(http.Flusher) keep their answer. http.ResponseController reaches the
+// wrapped writer through Unwrap.
+type StatusRecorder struct {
+	http.ResponseWriter
+	Status    int
+	Bytes     int64
+	FirstByte time.Time // when headers were sent, zero if nothing was written
+	Hijacked  bool
+	Streaming bool // handler kept writing after flushing a part of the response
+
+	wroteHeader bool
+	flushed     bool
+	writer      http.ResponseWriter
+}
+
+// NewStatusRecorder wraps w, Status defaults to 200.
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	r := &StatusRecorder{ResponseWriter: w, Status: http.StatusOK}
+	r.writer = r.wrap()
+	return r
+}
+
+// Writer returns the writer to pass to the handler.
+func (r *StatusRecorder) Writer() http.ResponseWriter {
+	if r.writer == nil {
+		r.writer = r.wrap()
+	}
+	return r.writer
+}
+
+func (r *StatusRecorder) WriteHeader(code int) {
+	// 1xx informational headers may precede the final one
+	if !r.wroteHeader && code >= 200 {
+		r.Status = code
+		r.markWritten()
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *StatusRecorder) Write(b []byte) (int, error) {
+	r.implicitHeader()
+	n, err := r.ResponseWriter.Write(b)
+	r.wrote(int64(n))
+	return n, err
+}
+
+// Unwrap is used by http.ResponseController.
+func (r *StatusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// LongLived reports whether the response is a hijacked connection, an event stream
+// or streamed in flushed parts, whose duration says nothing about handler latency.
+func (r *StatusRecorder) LongLived() bool {
+	return r.Hijacked || r.Streaming || IsEventStream(r.Header())
+}
+
+// TTFB returns the time from start until headers were sent, or 0 if nothing was written.
+func (r *StatusRecorder) TTFB(start time.Time) time.Duration {
+	if r.FirstByte.IsZero() {
+		return 0
+	}
+	return r.FirstByte.Sub(start)
+}
+
+// readFrom keeps the sendfile/splice fast path of the wrapped writer.
+func (r *StatusRecorder) readFrom(src io.Reader) (int64, error) {
+	r.implicitHeader()
+	n, err := r.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	r.wrote(n)
+	return n, err
+}
+
+func (r *StatusRecorder) flush() {
+	r.implicitHeader()
+	r.flushed = true
+	r.ResponseWriter.(http.Flusher).Flush()
+}
+
+func (r *StatusRecorder) hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := r.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		r.Hijacked = true
+		if !r.wroteHeader {
+			// The handler answers on the raw connection, typically with a protocol switch
+			r.Status = http.StatusSwitchingProtocols
+			r.markWritten()
+		}
+	}
+	return conn, rw, err
+}
+
+func (r *StatusRecorder) push(target string, opts *http.PushOptions) error {
+	return r.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+// wrote counts n body bytes, writing after a flush makes the response streaming.
+func (r *StatusRecorder) wrote(n int64) {
+	r.Bytes += n
+	if r.flushed && n > 0 {
+		r.Streaming = true
+	}
+}
+
+// implicitHeader records the 200 that net/http sends on the first Write or Flush.
+func (r *StatusRecorder) implicitHeader() {
+	if !r.wroteHeader {
+		r.Status = http.StatusOK
+		r.markWritten()
+	}
+}
+
+func (r *StatusRecorder) markWritten() {
+	r.wroteHeader = true
+	r.FirstByte = time.Now()
+}
+
+// IsEventStream reports whether response headers announce Server-Sent Events.
+func IsEventStream(h http.Header) bool {
+	return IsEventStreamType(h.Get("Content-Type"))
+}
+
+// IsEventStreamType reports whether a Content-Type value is text/event-stream.
+func IsEventStreamType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/event-stream"
+}
+
+// The func types adapt the recorder's methods to the optional interfaces,
+// wrap combines them with the base writer.
+type (
+	flusherFunc    func()
+	hijackerFunc   func() (net.Conn, *bufio.ReadWriter, error)
+	pusherFunc     func(string, *http.PushOptions) error
+	readerFromFunc func(io.Reader) (int64, error)
+
+	// baseWriter — what every wrapped writer implements
+	baseWriter interface {
+		http.ResponseWriter
+		Unwrap() http.ResponseWriter
+	}
+)
+
+func (f flusherFunc) Flush()                                        { f() }
+func (f hijackerFunc) Hijack() (net.Conn, *bufio.ReadWriter, error) { return f() }
+func (f pusherFunc) Push(target string, opts *http.PushOptions) error {
+	return f(target, opts)
+}
+func (f readerFromFunc) ReadFrom(src io.Reader) (int64, error) { return f(src) }
+
+// wrap builds a writer with one struct type per combination of optional interfaces,
+// so type assertions on it succeed only where they succeed on the wrapped writer.
+func (r *StatusRecorder) wrap() http.ResponseWriter {
+	_, f := r.ResponseWriter.(http.Flusher)
+	_, h := r.ResponseWriter.(http.Hijacker)
+	_, p := r.ResponseWriter.(http.Pusher)
+	_, rf := r.ResponseWriter.(io.ReaderFrom)
+
+	var base baseWriter = r
+	flush, hijack, push, readFrom := flusherFunc(r.flush), hijackerFunc(r.hijack), pusherFunc(r.push), readerFromFunc(r.readFrom)
+
+	switch {
+	case f && h && p && rf:
+		return struct {
+			baseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{base, flush, hijack, push, readFrom}
+	case f && h && p:
+		return struct {
+			baseWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{base, flush, hijack, push}
+	case f && h && rf:
+		return struct {
+			baseWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{base, flush, hijack, readFrom}
+	case f && p && rf:
+		return struct {
+			baseWriter
+			http.Flusher
+			http.Pusher
+			io.ReaderFrom
+		}{base, flush, push, readFrom}
+	case h && p && rf:
+		return struct {
+			baseWriter
+			http.Hijacker
+			http.Pusher
+			io.ReaderFrom
+		}{base, hijack, push, readFrom}
+	case f && h:
+		return struct {
+			baseWriter
+			http.Flusher
+			http.Hijacker
+		}{base, flush, hijack}
+	case f && p:
+		return struct {
+			baseWriter
+			http.Flusher
+			http.Pusher
+		}{base, flush, push}
+	case f && rf:
+		return struct {
+			baseWriter
+			http.Flusher
+			io.ReaderFrom
+		}{base, flush, readFrom}
+	case h && p:
+		return struct {
+			baseWriter
+			http.Hijacker
+			http.Pusher
+		}{base, hijack, push}
+	case h && rf:
+		return struct {
+			baseWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{base, hijack, readFrom}
+	case p && rf:
+		return struct {
+			baseWriter
+			http.Pusher
+			io.ReaderFrom
+		}{base, push, readFrom}
+	case f:
+		return struct {
+			baseWriter
+			http.Flusher
+		}{base, flush}
+	case h:
+		return struct {
+			baseWriter
+			http.Hijacker
+		}{base, hijack}
+	case p:
+		return struct {
+			baseWriter
+			http.Pusher
+		}{base, push}
+	case rf:
+		return struct {
+			baseWriter
+			io.ReaderFrom
+		}{base, readFrom}
+	default:
+		return struct{ baseWriter }{base}
+	}
+}
@@ -0,0 +1,116 @@
+package model
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// plainWriter implements nothing beyond http.ResponseWriter
+type plainWriter struct {
+	header http.Header
+	body   strings.Builder
+	code   int
+}
+
+func (w *plainWriter) Header() http.Header         { return w.header }
+func (w *plainWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *plainWriter) WriteHeader(code int)        { w.code = code }
+
+func interfaces(w http.ResponseWriter) (f, h, p, rf bool) {
+	_, f = w.(http.Flusher)
+	_, h = w.(http.Hijacker)
+	_, p = w.(http.Pusher)
+	_, rf = w.(io.ReaderFrom)
+	return
+}
+
+func TestWriterKeepsInterfaceSet(t *testing.T) {
+	plain := &plainWriter{header: http.Header{}}
+	if f, h, p, rf := interfaces(NewStatusRecorder(plain).Writer()); f || h || p || rf {
+		t.Errorf("plain writer: Flusher %v, Hijacker %v, Pusher %v, ReaderFrom %v, want none", f, h, p, rf)
+	}
+
+	// httptest.ResponseRecorder only flushes
+	if f, h, p, rf := interfaces(NewStatusRecorder(httptest.NewRecorder()).Writer()); !f || h || p || rf {
+		t.Errorf("ResponseRecorder: Flusher %v, Hijacker %v, Pusher %v, ReaderFrom %v, want Flusher only", f, h, p, rf)
+	}
+
+	// The HTTP/1.1 server writer flushes, hijacks and reads from files, but cannot push
+	got := make(chan [4]bool, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, h, p, rf := interfaces(NewStatusRecorder(w).Writer())
+		got <- [4]bool{f, h, p, rf}
+	}))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if ifaces := <-got; ifaces != [4]bool{true, true, false, true} {
+		t.Errorf("server writer: Flusher, Hijacker, Pusher, ReaderFrom = %v, want [true true false true]", ifaces)
+	}
+}
+
+func TestResponseControllerUnwraps(t *testing.T) {
+	sr := NewStatusRecorder(&plainWriter{header: http.Header{}})
+	if err := http.NewResponseController(sr.Writer()).Flush(); err == nil {
+		t.Error("Flush over a writer that cannot flush succeeded")
+	}
+
+	rec := httptest.NewRecorder()
+	sr = NewStatusRecorder(rec)
+	if err := http.NewResponseController(sr.Writer()).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Flushed || sr.Status != http.StatusOK || sr.FirstByte.IsZero() {
+		t.Errorf("flushed %v, status %d, first byte %v", rec.Flushed, sr.Status, sr.FirstByte)
+	}
+}
+
+func TestLongLived(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		handler func(w http.ResponseWriter)
+		want    bool
+	}{
+		{"plain", func(w http.ResponseWriter) {
+			io.WriteString(w, "hello")
+		}, false},
+		{"flush at the end", func(w http.ResponseWriter) {
+			io.WriteString(w, "hello")
+			w.(http.Flusher).Flush()
+		}, false},
+		{"written after flush", func(w http.ResponseWriter) {
+			io.WriteString(w, "part 1")
+			w.(http.Flusher).Flush()
+			io.WriteString(w, "part 2")
+		}, true},
+		{"event stream", func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+			w.WriteHeader(http.StatusOK)
+		}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := NewStatusRecorder(httptest.NewRecorder())
+			tt.handler(sr.Writer())
+			if got := sr.LongLived(); got != tt.want {
+				t.Errorf("LongLived() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImplicitStatusAndBytes(t *testing.T) {
+	sr := NewStatusRecorder(&plainWriter{header: http.Header{}})
+	w := sr.Writer()
+	w.WriteHeader(http.StatusEarlyHints)
+	io.WriteString(w, "abc")
+	w.WriteHeader(http.StatusTeapot) // superfluous, the 200 was already sent
+	if sr.Status != http.StatusOK || sr.Bytes != 3 {
+		t.Errorf("status %d, bytes %d, want 200 and 3", sr.Status, sr.Bytes)
+	}
+}
@@ -1,75 +1,82 @@
 package monitor
 
 import (
-	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/aurieli333/goapimon/config"
+	"github.com/aurieli333/goapimon/filter"
 	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/normalize"
+	"github.com/aurieli333/goapimon/store"
 )
 
 type Monitor struct {
-	Stats map[string]map[string]*model.RouteStats // method -> path -> stats
-	Mu    *config.SafeMutex
+	Store *store.Store
+
+	// Normalizer maps raw URL paths to routes, nil means normalize.Default
+	Normalizer normalize.Normalizer
+
+	// Filter selects monitored requests, nil monitors everything but internal paths
+	Filter *filter.Filter
+
+	// internal paths (dashboard, metrics) are read on every request and changed rarely
+	internalMu sync.Mutex
+	internal   atomic.Pointer[[]filter.Rule]
 }
 
-func NewMonitor(stats map[string]map[string]*model.RouteStats) *Monitor {
+func NewMonitor(s *store.Store) *Monitor {
 	return &Monitor{
-		Stats: stats,
-		Mu:    &config.SafeMutex{},
+		Store: s,
 	}
 }
 
 func (m *Monitor) CoreMiddleware(method string, path string, status int, start time.Time, elapsed time.Duration) {
-	m.Mu.Lock()
-	defer m.Mu.Unlock()
-
-	methodStats, ok := m.Stats[method]
-	if !ok {
-		methodStats = make(map[string]*model.RouteStats)
-		m.Stats[method] = methodStats
-	}
-
-	rs, ok := methodStats[path]
-	if !ok {
-		rs = &model.RouteStats{
-			TotalStatus: make(map[int]int),
-			TotalMin:    elapsed,
-			TotalMax:    elapsed,
-			FirstSeen:   start,
-		}
-		methodStats[path] = rs
-	}
-
-	// add new data
-	rs.Recent = append(rs.Recent, model.RequestRecord{
+	m.Record(model.RequestRecord{
 		Timestamp: start,
 		Duration:  elapsed,
 		Status:    status,
 		Method:    method,
+		Path:      path,
 	})
+}
 
-	// Delete old data (more then 5 minutes)
-	cutoff := time.Now().Add(-5 * time.Minute)
-	idx := slices.IndexFunc(rs.Recent, func(rec model.RequestRecord) bool {
-		return rec.Timestamp.After(cutoff)
-	})
-	if idx > 0 {
-		rs.Recent = slices.Delete(rs.Recent, 0, idx)
-	}
+// Record stores a fully described request, e.g. one carrying a trace ID.
+func (m *Monitor) Record(rec model.RequestRecord) {
+	m.Store.Record(rec)
+}
 
-	// Refresh aggregates
-	rs.TotalCount++
-	rs.TotalStatus[status]++
-	rs.TotalTime += elapsed
-	if elapsed < rs.TotalMin {
-		rs.TotalMin = elapsed
+// Monitored reports whether adapters should record a request:
+// it passes Filter and is not served by goapimon itself.
+func (m *Monitor) Monitored(method, path string) bool {
+	if internal := m.internal.Load(); internal != nil {
+		for _, r := range *internal {
+			if r.Match(method, path) {
+				return false
+			}
+		}
 	}
-	if elapsed > rs.TotalMax {
-		rs.TotalMax = elapsed
+	return m.Filter.Monitored(method, path)
+}
+
+// ExcludeInternal adds paths served by goapimon, such as the dashboard or the metrics endpoint.
+// It is safe to call while requests are recorded.
+func (m *Monitor) ExcludeInternal(rules ...filter.Rule) {
+	m.internalMu.Lock()
+	defer m.internalMu.Unlock()
+
+	var next []filter.Rule
+	if cur := m.internal.Load(); cur != nil {
+		next = append(next, *cur...)
 	}
-	rs.LastSeen = time.Now()
-	if status >= 400 {
-		rs.TotalErrorCount++
+	next = append(next, rules...)
+	m.internal.Store(&next)
+}
+
+// NormalizePath maps a raw URL path to its route with the configured Normalizer.
+func (m *Monitor) NormalizePath(path string) string {
+	if m.Normalizer == nil {
+		return normalize.Default.Normalize(path)
 	}
+	return m.Normalizer.Normalize(path)
 }
@@ -0,0 +1,193 @@
+// Package normalize turns raw URL paths into route labels,
+// so /users/42 and /users/43 are counted as one route /users/:id.
+package normalize
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Normalizer — maps a raw URL path to the route it is recorded under
+type Normalizer interface {
+	Normalize(path string) string
+}
+
+// NormalizerFunc — adapter to use an ordinary function as a Normalizer
+type NormalizerFunc func(path string) string
+
+func (f NormalizerFunc) Normalize(path string) string {
+	return f(path)
+}
+
+// Rule — one step of path normalization.
+// Rewrite returns the new path and done=true when the result is final
+// and later rules must not touch it.
+type Rule interface {
+	Rewrite(path string) (result string, done bool)
+}
+
+// Rules — Normalizer applying rules in order, without the built-in ones
+type Rules []Rule
+
+func (rs Rules) Normalize(path string) string {
+	for _, r := range rs {
+		var done bool
+		if path, done = r.Rewrite(path); done {
+			break
+		}
+	}
+	return path
+}
+
+// New — Normalizer applying rules first, then the built-in segment rules
+func New(rules ...Rule) Normalizer {
+	rs := make(Rules, 0, len(rules)+1)
+	rs = append(rs, rules...)
+	return append(rs, Segments(BuiltinSegments...))
+}
+
+// Default — built-in rules only, used when nothing is configured
+var Default = New()
+
+// SegmentRule — replaces a whole path segment matched by Match with Placeholder
+type SegmentRule struct {
+	Placeholder string
+	Match       func(segment string) bool
+}
+
+// Segment — SegmentRule matching segments against re, which should be anchored
+func Segment(placeholder string, re *regexp.Regexp) SegmentRule {
+	return SegmentRule{Placeholder: placeholder, Match: re.MatchString}
+}
+
+var (
+	hasDigit = regexp.MustCompile(`[0-9]`)
+	hasLower = regexp.MustCompile(`[a-z]`)
+	hasUpper = regexp.MustCompile(`[A-Z]`)
+	hexRegex = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+	b64Regex = regexp.MustCompile(`^[A-Za-z0-9_-]{20,}={0,2}$`)
+)
+
+// Built-in segment rules
+var (
+	// Int — decimal IDs, e.g. 42
+	Int = Segment(":id", regexp.MustCompile(`^[0-9]+$`))
+	// UUID — e.g. 123e4567-e89b-12d3-a456-426614174000
+	UUID = Segment(":id", regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`))
+	// ULID — e.g. 01ARZ3NDEKTSV4RRFFQ69G5FAV
+	ULID = Segment(":id", regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Za-hjkmnp-tv-z]{25}$`))
+	// ObjectID — MongoDB ObjectID, e.g. 507f1f77bcf86cd799439011
+	ObjectID = Segment(":id", regexp.MustCompile(`^[0-9a-fA-F]{24}$`))
+	// Date — ISO 8601 calendar date, e.g. 2024-01-31
+	Date = Segment(":date", regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`))
+	// Email — e.g. jane@example.com
+	Email = Segment(":email", regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`))
+	// Hex — hashes and other hex strings of 16+ characters with at least one digit
+	Hex = SegmentRule{Placeholder: ":hash", Match: func(s string) bool {
+		return hexRegex.MatchString(s) && hasDigit.MatchString(s)
+	}}
+	// Token — base64-ish tokens of 20+ characters mixing digits, lower and upper case,
+	// lowercase slugs are left alone
+	Token = SegmentRule{Placeholder: ":token", Match: func(s string) bool {
+		return b64Regex.MatchString(s) && hasDigit.MatchString(s) && hasLower.MatchString(s) && hasUpper.MatchString(s)
+	}}
+)
+
+// BuiltinSegments — segment rules applied by New, more specific ones first
+var BuiltinSegments = []SegmentRule{Int, UUID, ObjectID, ULID, Date, Email, Hex, Token}
+
+type segments []SegmentRule
+
+// Segments — Rule replacing every path segment with the placeholder of the first matching rule
+func Segments(rules ...SegmentRule) Rule {
+	return segments(rules)
+}
+
+func (rs segments) Rewrite(path string) (string, bool) {
+	parts := strings.Split(path, "/")
+	changed := false
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		for _, r := range rs {
+			if r.Match(part) {
+				parts[i] = r.Placeholder
+				changed = true
+				break
+			}
+		}
+	}
+	if !changed {
+		return path, false
+	}
+	return strings.Join(parts, "/"), false
+}
+
+type regexRule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// Regex — Rule replacing every match of re in the path, as regexp.ReplaceAllString does
+func Regex(re *regexp.Regexp, replacement string) Rule {
+	return regexRule{re: re, replacement: replacement}
+}
+
+func (r regexRule) Rewrite(path string) (string, bool) {
+	return r.re.ReplaceAllString(path, r.replacement), false
+}
+
+type prefixRule struct {
+	prefix      string
+	replacement string
+}
+
+// Prefix — final Rule mapping every path starting with prefix to replacement,
+// e.g. Prefix("/static/", "/static/*")
+func Prefix(prefix, replacement string) Rule {
+	return prefixRule{prefix: prefix, replacement: replacement}
+}
+
+func (r prefixRule) Rewrite(path string) (string, bool) {
+	if strings.HasPrefix(path, r.prefix) {
+		return r.replacement, true
+	}
+	return path, false
+}
+
+type templateRule struct {
+	template string
+	parts    []string
+}
+
+// Template — final Rule mapping paths shaped like template to template itself.
+// Segments written as {name} or :name match any non-empty segment, the rest must be equal:
+// Template("/posts/{slug}") records /posts/hello-world as /posts/{slug}.
+func Template(template string) Rule {
+	return templateRule{template: template, parts: strings.Split(template, "/")}
+}
+
+func (r templateRule) Rewrite(path string) (string, bool) {
+	parts := strings.Split(path, "/")
+	if len(parts) != len(r.parts) {
+		return path, false
+	}
+	for i, want := range r.parts {
+		if isParam(want) {
+			if parts[i] == "" {
+				return path, false
+			}
+			continue
+		}
+		if parts[i] != want {
+			return path, false
+		}
+	}
+	return r.template, true
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, ":") ||
+		len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
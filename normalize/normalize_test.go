@@ -0,0 +1,171 @@
+package normalize
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestBuiltinSegments(t *testing.T) {
+	for _, tt := range []struct {
+		name, path, want string
+	}{
+		// Int
+		{"int", "/users/42", "/users/:id"},
+		{"int twice", "/users/42/orders/7", "/users/:id/orders/:id"},
+		{"int with suffix", "/users/42abc", "/users/42abc"},
+		{"version", "/api/v1/users", "/api/v1/users"},
+
+		// UUID
+		{"uuid", "/orders/123e4567-e89b-12d3-a456-426614174000/items", "/orders/:id/items"},
+		{"uuid upper case", "/orders/123E4567-E89B-12D3-A456-426614174000", "/orders/:id"},
+		{"uuid too short", "/orders/123e4567-e89b-12d3-a456-42661417400", "/orders/123e4567-e89b-12d3-a456-42661417400"},
+
+		// ULID
+		{"ulid", "/events/01ARZ3NDEKTSV4RRFFQ69G5FAV", "/events/:id"},
+		{"ulid lower case", "/events/01arz3ndektsv4rrffq69g5fav", "/events/:id"},
+		{"ulid out of range", "/events/81ARZ3NDEKTSV4RRFFQ69G5FAV", "/events/81ARZ3NDEKTSV4RRFFQ69G5FAV"},
+		{"ulid with U", "/events/01ARZ3NDEKTSV4RRFFQ69G5FAU", "/events/01ARZ3NDEKTSV4RRFFQ69G5FAU"},
+
+		// ObjectID
+		{"objectid", "/docs/507f1f77bcf86cd799439011", "/docs/:id"},
+
+		// Date
+		{"date", "/reports/2024-01-31", "/reports/:date"},
+		{"date unpadded", "/reports/2024-1-31", "/reports/2024-1-31"},
+
+		// Email
+		{"email", "/users/jane@example.com/settings", "/users/:email/settings"},
+		{"handle", "/users/@jane", "/users/@jane"},
+
+		// Hex
+		{"md5", "/blobs/d41d8cd98f00b204e9800998ecf8427e", "/blobs/:hash"},
+		{"sha1", "/commits/da39a3ee5e6b4b0d3255bfef95601890afd80709", "/commits/:hash"},
+		{"short hex", "/colors/ff00aa", "/colors/ff00aa"},
+		{"hex letters only", "/words/deadbeefdeadbeef", "/words/deadbeefdeadbeef"},
+
+		// Token
+		{"token", "/share/aGVsbG8gd29ybGQhIFRoaXMgaXM", "/share/:token"},
+		{"token with padding", "/share/aGVsbG8gd29ybGQhIFRoaXM=", "/share/:token"},
+		{"lowercase slug", "/posts/how-to-write-better-go-code-2024", "/posts/how-to-write-better-go-code-2024"},
+		{"camel case word", "/docs/GettingStartedWithGoapimon", "/docs/GettingStartedWithGoapimon"},
+
+		// Shape of the path is kept
+		{"root", "/", "/"},
+		{"empty segment", "/users//42", "/users//:id"},
+		{"trailing slash", "/users/42/", "/users/:id/"},
+		{"static", "/healthz", "/healthz"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Default.Normalize(tt.path); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserRules(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		rules []Rule
+		path  string
+		want  string
+	}{
+		{
+			"regex then built-ins",
+			[]Rule{Regex(regexp.MustCompile(`^/legacy/`), "/")},
+			"/legacy/users/42", "/users/:id",
+		},
+		{
+			"regex replaces every match",
+			[]Rule{Regex(regexp.MustCompile(`\.(json|xml)$`), "")},
+			"/users/42.json", "/users/:id",
+		},
+		{
+			"prefix is final",
+			[]Rule{Prefix("/static/", "/static/*")},
+			"/static/42/logo.png", "/static/*",
+		},
+		{
+			"prefix not matching",
+			[]Rule{Prefix("/static/", "/static/*")},
+			"/users/42", "/users/:id",
+		},
+		{
+			"template is final",
+			[]Rule{Template("/posts/{slug}")},
+			"/posts/2024", "/posts/{slug}",
+		},
+		{
+			"template with colon param",
+			[]Rule{Template("/users/:name/profile")},
+			"/users/jane/profile", "/users/:name/profile",
+		},
+		{
+			"template of other length",
+			[]Rule{Template("/posts/{slug}")},
+			"/posts/2024/comments", "/posts/:id/comments",
+		},
+		{
+			"template needs non-empty param",
+			[]Rule{Template("/posts/{slug}")},
+			"/posts/", "/posts/",
+		},
+		{
+			"template literal must be equal",
+			[]Rule{Template("/posts/{slug}")},
+			"/pages/42", "/pages/:id",
+		},
+		{
+			"first final rule wins",
+			[]Rule{Prefix("/a/", "/a/*"), Template("/a/{x}")},
+			"/a/b", "/a/*",
+		},
+		{
+			"order decides",
+			[]Rule{Template("/a/{x}"), Prefix("/a/", "/a/*")},
+			"/a/b", "/a/{x}",
+		},
+		{
+			"regex feeds later rules",
+			[]Rule{Regex(regexp.MustCompile(`\.json$`), ""), Template("/items/{id}")},
+			"/items/9.json", "/items/{id}",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.rules...).Normalize(tt.path); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// ruleFunc counts how often it runs
+type ruleFunc func(path string) (string, bool)
+
+func (f ruleFunc) Rewrite(path string) (string, bool) { return f(path) }
+
+func TestDoneShortCircuits(t *testing.T) {
+	calls := 0
+	after := ruleFunc(func(path string) (string, bool) {
+		calls++
+		return "/rewritten", false
+	})
+	rules := Rules{Prefix("/static/", "/static/*"), after}
+
+	if got := rules.Normalize("/static/app.js"); got != "/static/*" || calls != 0 {
+		t.Errorf("final rule: got %q and %d later calls, want /static/* and 0", got, calls)
+	}
+	if got := rules.Normalize("/users/42"); got != "/rewritten" || calls != 1 {
+		t.Errorf("non-final rule: got %q and %d later calls, want /rewritten and 1", got, calls)
+	}
+}
+
+func TestRulesWithoutBuiltins(t *testing.T) {
+	rules := Rules{Segments(Date)}
+	if got := rules.Normalize("/users/42/2024-01-31"); got != "/users/42/:date" {
+		t.Errorf("got %q, want /users/42/:date", got)
+	}
+	if got := NormalizerFunc(func(string) string { return "/x" }).Normalize("/users/42"); got != "/x" {
+		t.Errorf("NormalizerFunc: got %q", got)
+	}
+}
@@ -0,0 +1,242 @@
+package goapimon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aurieli333/goapimon/auth"
+	"github.com/aurieli333/goapimon/config"
+	"github.com/aurieli333/goapimon/dashboard"
+	"github.com/aurieli333/goapimon/filter"
+	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/normalize"
+	"github.com/aurieli333/goapimon/prometheus"
+	"github.com/aurieli333/goapimon/store"
+)
+
+// Define default time windows for analysis
+var defaultWindows = []model.Window{
+	{Name: "1m", Length: 1 * time.Minute},
+	{Name: "2m", Length: 2 * time.Minute},
+	{Name: "5m", Length: 5 * time.Minute},
+	// Add more windows here if needed
+}
+
+// DefaultBucketWidth — time slot aggregated into one storage bucket
+const DefaultBucketWidth = 10 * time.Second
+
+// Defaults of the downsampled per-route history charted by the dashboard
+const (
+	DefaultHistoryInterval  = 10 * time.Second
+	DefaultHistoryRetention = time.Hour
+)
+
+// DefaultRequestSamples — slowest and failed requests kept per route for the dashboard
+const DefaultRequestSamples = 10
+
+// DefaultMaxRoutesPerMethod — distinct paths kept per method before new ones go to model.OtherRoute
+const DefaultMaxRoutesPerMethod = 1000
+
+// Options — configuration for a goapimon instance
+type Options struct {
+	// Windows used by the dashboard and Prometheus. Defaults to 1m, 2m and 5m.
+	Windows []model.Window
+
+	// Retention — how long windowed data is kept.
+	// Defaults to the largest window; must not be shorter than any window.
+	Retention time.Duration
+
+	// BucketWidth — resolution of windowed data. Defaults to DefaultBucketWidth,
+	// must not be longer than the smallest window.
+	BucketWidth time.Duration
+
+	// HistoryRetention — how long per-route history is kept for the dashboard's time-series charts.
+	// Defaults to DefaultHistoryRetention, negative disables history.
+	HistoryRetention time.Duration
+
+	// HistoryInterval — resolution of per-route history. Defaults to DefaultHistoryInterval,
+	// must not be longer than HistoryRetention.
+	HistoryInterval time.Duration
+
+	// RequestSamples — how many of the slowest and of the latest failed requests
+	// the dashboard lists per route. Defaults to DefaultRequestSamples, negative disables them.
+	RequestSamples int
+
+	// HistogramBuckets — latency histogram upper bounds in seconds, ascending.
+	// Defaults to store.DefaultHistogramBuckets.
+	HistogramBuckets []float64
+
+	// PrometheusFormat — metrics written by the Prometheus handler.
+	// Defaults to prometheus.FormatLegacy, set prometheus.FormatStandard for counters and histograms.
+	PrometheusFormat prometheus.Format
+
+	// PathRules — normalization rules for raw URL paths, applied before the built-in ones
+	// (numeric IDs, UUID, ULID, ObjectID, dates, emails, hex hashes and tokens).
+	// Route templates reported by routers are kept as they are.
+	PathRules []normalize.Rule
+
+	// Normalizer — replaces PathRules and the built-in rules entirely when set.
+	Normalizer normalize.Normalizer
+
+	// MaxRoutesPerMethod — distinct paths recorded per method, requests to further paths
+	// are grouped under model.OtherRoute. Defaults to DefaultMaxRoutesPerMethod, negative means unlimited.
+	MaxRoutesPerMethod int
+
+	// Exclude — requests not monitored, e.g. filter.Exact("/healthz"), filter.Prefix("/static/"),
+	// filter.Glob("/*.ico") or filter.Methods("OPTIONS"). The dashboard and the Prometheus path
+	// are always excluded.
+	Exclude []filter.Rule
+
+	// Include — when set, only matching requests are monitored; Exclude still applies.
+	Include []filter.Rule
+
+	// DashboardPath — where the dashboard is mounted, e.g. "/internal/monitor/".
+	// Defaults to config.DashboardPath, a trailing slash is added when missing.
+	// "/" is rejected: everything under the dashboard is excluded from monitoring.
+	DashboardPath string
+
+	// Auth — access rules for the dashboard, its JSON and CSV exports and the metrics endpoint:
+	// bcrypt basic auth, bearer tokens, an IP/CIDR allowlist and a custom check.
+	// The zero value allows everyone.
+	Auth auth.Config
+
+	// DashboardPushInterval — how often the live dashboard is updated.
+	// Defaults to dashboard.DefaultPushInterval.
+	DashboardPushInterval time.Duration
+}
+
+// withDefaults fills unset fields and validates the result
+func (o Options) withDefaults() (Options, error) {
+	if len(o.Windows) == 0 {
+		o.Windows = defaultWindows
+	}
+	if err := validateWindows(o.Windows); err != nil {
+		return o, err
+	}
+	o.Windows = append([]model.Window(nil), o.Windows...)
+
+	shortest, longest := o.Windows[0], o.Windows[0]
+	for _, w := range o.Windows {
+		if w.Length < shortest.Length {
+			shortest = w
+		}
+		if w.Length > longest.Length {
+			longest = w
+		}
+	}
+
+	if o.Retention < 0 {
+		return o, errors.New("goapimon: retention must not be negative")
+	}
+	if o.Retention == 0 {
+		o.Retention = longest.Length
+	}
+	if longest.Length > o.Retention {
+		return o, fmt.Errorf("goapimon: window %q (%s) is longer than retention %s", longest.Name, longest.Length, o.Retention)
+	}
+
+	if o.BucketWidth < 0 {
+		return o, errors.New("goapimon: bucket width must not be negative")
+	}
+	if o.BucketWidth == 0 {
+		o.BucketWidth = min(DefaultBucketWidth, shortest.Length)
+	}
+	if o.BucketWidth > shortest.Length {
+		return o, fmt.Errorf("goapimon: bucket width %s is longer than window %q (%s)", o.BucketWidth, shortest.Name, shortest.Length)
+	}
+	if o.HistoryInterval < 0 {
+		return o, errors.New("goapimon: history interval must not be negative")
+	}
+	if o.HistoryInterval == 0 {
+		o.HistoryInterval = DefaultHistoryInterval
+	}
+	if o.HistoryRetention == 0 {
+		o.HistoryRetention = DefaultHistoryRetention
+	}
+	if o.HistoryRetention > 0 && o.HistoryInterval > o.HistoryRetention {
+		return o, fmt.Errorf("goapimon: history interval %s is longer than history retention %s", o.HistoryInterval, o.HistoryRetention)
+	}
+	if o.RequestSamples == 0 {
+		o.RequestSamples = DefaultRequestSamples
+	}
+	if len(o.HistogramBuckets) == 0 {
+		o.HistogramBuckets = store.DefaultHistogramBuckets
+	}
+	if err := validateHistogramBuckets(o.HistogramBuckets); err != nil {
+		return o, err
+	}
+	if o.DashboardPath == "" {
+		o.DashboardPath = config.DashboardPath
+	}
+	if !strings.HasPrefix(o.DashboardPath, "/") {
+		return o, fmt.Errorf("goapimon: dashboard path %q must start with /", o.DashboardPath)
+	}
+	if !strings.HasSuffix(o.DashboardPath, "/") {
+		o.DashboardPath += "/"
+	}
+	if o.DashboardPath == "/" {
+		return o, errors.New(`goapimon: dashboard path "/" would exclude every request from monitoring`)
+	}
+	if o.DashboardPushInterval < 0 {
+		return o, errors.New("goapimon: dashboard push interval must not be negative")
+	}
+	if o.DashboardPushInterval == 0 {
+		o.DashboardPushInterval = dashboard.DefaultPushInterval
+	}
+	if err := o.filter().Validate(); err != nil {
+		return o, fmt.Errorf("goapimon: %w", err)
+	}
+	if o.MaxRoutesPerMethod == 0 {
+		o.MaxRoutesPerMethod = DefaultMaxRoutesPerMethod
+	}
+	if o.Normalizer == nil {
+		o.Normalizer = normalize.New(o.PathRules...)
+	}
+	return o, nil
+}
+
+// filter returns the configured include/exclude lists, nil when there are none
+func (o Options) filter() *filter.Filter {
+	if len(o.Include) == 0 && len(o.Exclude) == 0 {
+		return nil
+	}
+	return &filter.Filter{
+		Include: append([]filter.Rule(nil), o.Include...),
+		Exclude: append([]filter.Rule(nil), o.Exclude...),
+	}
+}
+
+func validateHistogramBuckets(bounds []float64) error {
+	for i, b := range bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) || b <= 0 {
+			return fmt.Errorf("goapimon: histogram bucket %v must be a positive finite number", b)
+		}
+		if i > 0 && b <= bounds[i-1] {
+			return fmt.Errorf("goapimon: histogram buckets must be strictly ascending, got %v after %v", b, bounds[i-1])
+		}
+	}
+	return nil
+}
+
+func validateWindows(windows []model.Window) error {
+	seen := make(map[string]bool, len(windows))
+	for _, w := range windows {
+		if w.Name == "" {
+			return errors.New("goapimon: window name is empty")
+		}
+		if w.Name == "total" {
+			return errors.New(`goapimon: window name "total" is reserved`)
+		}
+		if seen[w.Name] {
+			return fmt.Errorf("goapimon: duplicate window %q", w.Name)
+		}
+		if w.Length <= 0 {
+			return fmt.Errorf("goapimon: window %q must have a positive length", w.Name)
+		}
+		seen[w.Name] = true
+	}
+	return nil
+}
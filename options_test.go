@@ -0,0 +1,33 @@
+package goapimon
+
+import (
+	"testing"
+
+	"github.com/aurieli333/goapimon/config"
+)
+
+func TestDashboardPath(t *testing.T) {
+	for _, tt := range []struct {
+		path, want string
+		fails      bool
+	}{
+		{"", config.DashboardPath, false},
+		{"/internal/monitor", "/internal/monitor/", false},
+		{"/internal/monitor/", "/internal/monitor/", false},
+		{"/", "", true},
+		{"internal", "", true},
+	} {
+		o, err := Options{DashboardPath: tt.path}.withDefaults()
+		if tt.fails {
+			if err == nil {
+				t.Errorf("DashboardPath %q accepted", tt.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("DashboardPath %q: %v", tt.path, err)
+		} else if o.DashboardPath != tt.want {
+			t.Errorf("DashboardPath %q became %q, want %q", tt.path, o.DashboardPath, tt.want)
+		}
+	}
+}
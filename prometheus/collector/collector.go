@@ -0,0 +1,220 @@
+// Package collector exposes goapimon statistics through a client_golang registry,
+// for services that already serve /metrics with prometheus/client_golang.
+// It is a separate module, so the core module does not depend on client_golang.
+package collector
+
+import (
+	"strconv"
+
+	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/store"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector implements prometheus.Collector on top of a goapimon store.
+// It reports the same series as the built-in exporter in prometheus.FormatStandard.
+type Collector struct {
+	Store *store.Store
+
+	requests *prometheus.Desc
+	errors   *prometheus.Desc
+	reqSize  *prometheus.Desc
+	respSize *prometheus.Desc
+	duration *prometheus.Desc
+
+	grpcRequests *prometheus.Desc
+	msgsReceived *prometheus.Desc
+	msgsSent     *prometheus.Desc
+
+	outRequests  *prometheus.Desc
+	outTransport *prometheus.Desc
+	outErrors    *prometheus.Desc
+	outDuration  *prometheus.Desc
+
+	routes        *prometheus.Desc
+	routesDropped *prometheus.Desc
+}
+
+func NewCollector(s *store.Store) *Collector {
+	return &Collector{
+		Store: s,
+		requests: prometheus.NewDesc(
+			"goapimon_requests_total",
+			"Total number of handled HTTP requests.",
+			[]string{"method", "path", "code"}, nil,
+		),
+		errors: prometheus.NewDesc(
+			"goapimon_request_errors_total",
+			"Total number of requests answered with status 400 or higher or a gRPC code other than OK.",
+			[]string{"method", "path"}, nil,
+		),
+		reqSize: prometheus.NewDesc(
+			"goapimon_request_bytes_total",
+			"Total size of request bodies in bytes.",
+			[]string{"method", "path"}, nil,
+		),
+		respSize: prometheus.NewDesc(
+			"goapimon_response_bytes_total",
+			"Total size of response bodies in bytes.",
+			[]string{"method", "path"}, nil,
+		),
+		duration: prometheus.NewDesc(
+			"goapimon_request_duration_seconds",
+			"Request latency in seconds.",
+			[]string{"method", "path"}, nil,
+		),
+		grpcRequests: prometheus.NewDesc(
+			"goapimon_grpc_requests_total",
+			"Total number of handled gRPC calls.",
+			[]string{"method", "path", "grpc_code"}, nil,
+		),
+		msgsReceived: prometheus.NewDesc(
+			"goapimon_grpc_stream_messages_received_total",
+			"Total number of messages received on gRPC streams.",
+			[]string{"method", "path"}, nil,
+		),
+		msgsSent: prometheus.NewDesc(
+			"goapimon_grpc_stream_messages_sent_total",
+			"Total number of messages sent on gRPC streams.",
+			[]string{"method", "path"}, nil,
+		),
+		outRequests: prometheus.NewDesc(
+			"goapimon_outbound_requests_total",
+			"Total number of outbound HTTP requests that received a response.",
+			[]string{"method", "host", "path", "code"}, nil,
+		),
+		outTransport: prometheus.NewDesc(
+			"goapimon_outbound_transport_errors_total",
+			"Total number of outbound HTTP requests that failed without a response.",
+			[]string{"method", "host", "path", "error"}, nil,
+		),
+		outErrors: prometheus.NewDesc(
+			"goapimon_outbound_request_errors_total",
+			"Total number of outbound requests answered with status 400 or higher or failed without a response.",
+			[]string{"method", "host", "path"}, nil,
+		),
+		outDuration: prometheus.NewDesc(
+			"goapimon_outbound_request_duration_seconds",
+			"Outbound request latency until response headers in seconds.",
+			[]string{"method", "host", "path"}, nil,
+		),
+		routes: prometheus.NewDesc(
+			"goapimon_routes",
+			"Number of distinct routes tracked per method.",
+			[]string{"method", "direction"}, nil,
+		),
+		routesDropped: prometheus.NewDesc(
+			"goapimon_routes_dropped_total",
+			"Total number of requests recorded under __other__ because their method reached the route limit.",
+			[]string{"method", "direction"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.requests
+	ch <- c.errors
+	ch <- c.reqSize
+	ch <- c.respSize
+	ch <- c.duration
+	ch <- c.grpcRequests
+	ch <- c.msgsReceived
+	ch <- c.msgsSent
+	ch <- c.outRequests
+	ch <- c.outTransport
+	ch <- c.outErrors
+	ch <- c.outDuration
+	ch <- c.routes
+	ch <- c.routesDropped
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	bounds := c.Store.HistogramBuckets()
+
+	for method, paths := range c.Store.Snapshot() {
+		for path, s := range paths {
+			if s.TotalGRPCStatus != nil {
+				for code, cnt := range s.TotalGRPCStatus {
+					ch <- metric(c.grpcRequests, prometheus.CounterValue, float64(cnt), method, path, code)
+				}
+				ch <- metric(c.msgsReceived, prometheus.CounterValue, float64(s.TotalMsgsReceived), method, path)
+				ch <- metric(c.msgsSent, prometheus.CounterValue, float64(s.TotalMsgsSent), method, path)
+			} else {
+				for code, cnt := range s.TotalStatus {
+					ch <- metric(c.requests, prometheus.CounterValue, float64(cnt), method, path, strconv.Itoa(code))
+				}
+			}
+			ch <- metric(c.errors, prometheus.CounterValue, float64(s.TotalErrorCount), method, path)
+			ch <- metric(c.reqSize, prometheus.CounterValue, float64(s.TotalReqBytes), method, path)
+			ch <- metric(c.respSize, prometheus.CounterValue, float64(s.TotalRespBytes), method, path)
+
+			ch <- histogram(c.duration, bounds, s, method, path)
+		}
+	}
+
+	for method, paths := range c.Store.OutboundSnapshot() {
+		for route, s := range paths {
+			host, path := model.SplitOutboundPath(route)
+			for code, cnt := range s.TotalStatus {
+				ch <- metric(c.outRequests, prometheus.CounterValue, float64(cnt), method, host, path, strconv.Itoa(code))
+			}
+			for kind, cnt := range s.TotalTransportErrors {
+				ch <- metric(c.outTransport, prometheus.CounterValue, float64(cnt), method, host, path, kind)
+			}
+			ch <- metric(c.outErrors, prometheus.CounterValue, float64(s.TotalErrorCount), method, host, path)
+			ch <- histogram(c.outDuration, bounds, s, method, host, path)
+		}
+	}
+
+	for _, rc := range c.Store.RouteCounts() {
+		ch <- metric(c.routes, prometheus.GaugeValue, float64(rc.Routes), rc.Method, rc.Direction())
+		ch <- metric(c.routesDropped, prometheus.CounterValue, float64(rc.Dropped), rc.Method, rc.Direction())
+	}
+}
+
+// metric builds a constant metric. Invalid label values are reported to the registry
+// as an error instead of panicking, Gather runs Collect on its own goroutine.
+func metric(desc *prometheus.Desc, typ prometheus.ValueType, value float64, labelValues ...string) prometheus.Metric {
+	m, err := prometheus.NewConstMetric(desc, typ, value, labelValues...)
+	if err != nil {
+		return prometheus.NewInvalidMetric(desc, err)
+	}
+	return m
+}
+
+// histogram builds the lifetime latency histogram of a route with its exemplars.
+func histogram(desc *prometheus.Desc, bounds []float64, s *model.RouteStats, labelValues ...string) prometheus.Metric {
+	buckets := make(map[float64]uint64, len(bounds))
+	var cumulative uint64
+	for i, bound := range bounds {
+		if i < len(s.TotalHistogram) {
+			cumulative += uint64(s.TotalHistogram[i])
+		}
+		buckets[bound] = cumulative
+	}
+	hist, err := prometheus.NewConstHistogram(desc, uint64(s.TotalCount-s.TotalLongLived), s.TotalTime.Seconds(), buckets, labelValues...)
+	if err != nil {
+		return prometheus.NewInvalidMetric(desc, err)
+	}
+
+	var exemplars []prometheus.Exemplar
+	for _, e := range s.TotalExemplars {
+		if e.TraceID == "" {
+			continue
+		}
+		exemplars = append(exemplars, prometheus.Exemplar{
+			Value:     e.Value,
+			Labels:    prometheus.Labels{"trace_id": e.TraceID},
+			Timestamp: e.Timestamp,
+		})
+	}
+	if len(exemplars) > 0 {
+		if m, err := prometheus.NewMetricWithExemplars(hist, exemplars...); err == nil {
+			hist = m
+		}
+	}
+	return hist
+}
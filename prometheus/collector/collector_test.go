@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/store"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestInvalidLabelValue gathers a path that is not valid UTF-8: the registry must
+// keep serving the other series instead of the process panicking.
+func TestInvalidLabelValue(t *testing.T) {
+	s := store.NewStore(store.Options{})
+	for _, path := range []string{"/ok", "/files/\xff"} {
+		s.Record(model.RequestRecord{Timestamp: time.Now(), Duration: time.Millisecond, Status: 200, Method: "GET", Path: path})
+	}
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(NewCollector(s))
+
+	mfs, _ := reg.Gather()
+	found := false
+	for _, mf := range mfs {
+		if mf.GetName() != "goapimon_requests_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "path" && l.GetValue() == "/ok" {
+					found = true
+				}
+			}
+		}
+	}
+	if !found {
+		t.Error("goapimon_requests_total{path=\"/ok\"} missing")
+	}
+}
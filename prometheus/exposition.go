@@ -0,0 +1,278 @@
+package prometheus
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/store"
+)
+
+// route is one method + path of a snapshot
+type route struct {
+	method string
+	path   string
+	stats  *model.RouteStats
+}
+
+// sortedRoutes flattens a snapshot ordered by method, then path,
+// so every scrape lists series in the same order.
+func sortedRoutes(stats map[string]map[string]*model.RouteStats) []route {
+	var routes []route
+	for method, paths := range stats {
+		for path, s := range paths {
+			routes = append(routes, route{method: method, path: path, stats: s})
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].method != routes[j].method {
+			return routes[i].method < routes[j].method
+		}
+		return routes[i].path < routes[j].path
+	})
+	return routes
+}
+
+func sortedCodes(status map[int]int) []int {
+	codes := make([]int, 0, len(status))
+	for code := range status {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	return codes
+}
+
+func sortedNames(status map[string]int) []string {
+	codes := make([]string, 0, len(status))
+	for code := range status {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// writeStandard emits lifetime counters and the latency histogram of served routes
+// and outbound calls, in OpenMetrics flavour with exemplars and the trailing # EOF when om is set.
+func writeStandard(w io.Writer, routes, outbound []route, counts []store.RouteCount, bounds []float64, om bool) {
+	var grpcRoutes []route
+	writeFamily(w, "goapimon_requests_total", "counter", "Total number of handled HTTP requests.", om)
+	for _, rt := range routes {
+		// gRPC calls are broken down by their own codes below
+		if rt.stats.TotalGRPCStatus != nil {
+			grpcRoutes = append(grpcRoutes, rt)
+			continue
+		}
+		base := labels{{"method", rt.method}, {"path", rt.path}}
+		for _, code := range sortedCodes(rt.stats.TotalStatus) {
+			writeMetric(w, "goapimon_requests_total", base.with("code", strconv.Itoa(code)), rt.stats.TotalStatus[code])
+		}
+	}
+	if len(grpcRoutes) > 0 {
+		writeGRPC(w, grpcRoutes, om)
+	}
+
+	writeFamily(w, "goapimon_request_errors_total", "counter", "Total number of requests answered with status 400 or higher or a gRPC code other than OK.", om)
+	for _, rt := range routes {
+		writeMetric(w, "goapimon_request_errors_total", labels{{"method", rt.method}, {"path", rt.path}}, rt.stats.TotalErrorCount)
+	}
+
+	writeFamily(w, "goapimon_request_bytes_total", "counter", "Total size of request bodies in bytes.", om)
+	for _, rt := range routes {
+		writeMetric(w, "goapimon_request_bytes_total", labels{{"method", rt.method}, {"path", rt.path}}, rt.stats.TotalReqBytes)
+	}
+
+	writeFamily(w, "goapimon_response_bytes_total", "counter", "Total size of response bodies in bytes.", om)
+	for _, rt := range routes {
+		writeMetric(w, "goapimon_response_bytes_total", labels{{"method", rt.method}, {"path", rt.path}}, rt.stats.TotalRespBytes)
+	}
+
+	writeFamily(w, "goapimon_request_duration_seconds", "histogram", "Request latency in seconds.", om)
+	for _, rt := range routes {
+		writeHistogram(w, "goapimon_request_duration_seconds", labels{{"method", rt.method}, {"path", rt.path}}, bounds, rt.stats, om)
+	}
+
+	if len(outbound) > 0 {
+		writeOutbound(w, outbound, bounds, om)
+	}
+
+	writeRouteCounts(w, counts, om)
+
+	if om {
+		fmt.Fprint(w, "# EOF\n")
+	}
+}
+
+// writeGRPC emits gRPC code and stream message counters.
+func writeGRPC(w io.Writer, routes []route, om bool) {
+	writeFamily(w, "goapimon_grpc_requests_total", "counter", "Total number of handled gRPC calls.", om)
+	for _, rt := range routes {
+		base := labels{{"method", rt.method}, {"path", rt.path}}
+		for _, code := range sortedNames(rt.stats.TotalGRPCStatus) {
+			writeMetric(w, "goapimon_grpc_requests_total", base.with("grpc_code", code), rt.stats.TotalGRPCStatus[code])
+		}
+	}
+
+	writeFamily(w, "goapimon_grpc_stream_messages_received_total", "counter", "Total number of messages received on gRPC streams.", om)
+	for _, rt := range routes {
+		writeMetric(w, "goapimon_grpc_stream_messages_received_total", labels{{"method", rt.method}, {"path", rt.path}}, rt.stats.TotalMsgsReceived)
+	}
+
+	writeFamily(w, "goapimon_grpc_stream_messages_sent_total", "counter", "Total number of messages sent on gRPC streams.", om)
+	for _, rt := range routes {
+		writeMetric(w, "goapimon_grpc_stream_messages_sent_total", labels{{"method", rt.method}, {"path", rt.path}}, rt.stats.TotalMsgsSent)
+	}
+}
+
+// writeOutbound emits counters and the latency histogram of outbound calls.
+func writeOutbound(w io.Writer, routes []route, bounds []float64, om bool) {
+	base := func(rt route) labels {
+		host, path := model.SplitOutboundPath(rt.path)
+		return labels{{"method", rt.method}, {"host", host}, {"path", path}}
+	}
+
+	writeFamily(w, "goapimon_outbound_requests_total", "counter", "Total number of outbound HTTP requests that received a response.", om)
+	for _, rt := range routes {
+		for _, code := range sortedCodes(rt.stats.TotalStatus) {
+			writeMetric(w, "goapimon_outbound_requests_total", base(rt).with("code", strconv.Itoa(code)), rt.stats.TotalStatus[code])
+		}
+	}
+
+	writeFamily(w, "goapimon_outbound_transport_errors_total", "counter", "Total number of outbound HTTP requests that failed without a response.", om)
+	for _, rt := range routes {
+		for _, kind := range sortedNames(rt.stats.TotalTransportErrors) {
+			writeMetric(w, "goapimon_outbound_transport_errors_total", base(rt).with("error", kind), rt.stats.TotalTransportErrors[kind])
+		}
+	}
+
+	writeFamily(w, "goapimon_outbound_request_errors_total", "counter", "Total number of outbound requests answered with status 400 or higher or failed without a response.", om)
+	for _, rt := range routes {
+		writeMetric(w, "goapimon_outbound_request_errors_total", base(rt), rt.stats.TotalErrorCount)
+	}
+
+	writeFamily(w, "goapimon_outbound_request_duration_seconds", "histogram", "Outbound request latency until response headers in seconds.", om)
+	for _, rt := range routes {
+		writeHistogram(w, "goapimon_outbound_request_duration_seconds", base(rt), bounds, rt.stats, om)
+	}
+}
+
+// writeRouteCounts emits the number of tracked routes and requests dropped by the route limit.
+func writeRouteCounts(w io.Writer, counts []store.RouteCount, om bool) {
+	writeFamily(w, "goapimon_routes", "gauge", "Number of distinct routes tracked per method.", om)
+	for _, c := range counts {
+		writeMetric(w, "goapimon_routes", labels{{"method", c.Method}, {"direction", c.Direction()}}, c.Routes)
+	}
+
+	writeFamily(w, "goapimon_routes_dropped_total", "counter", "Total number of requests recorded under __other__ because their method reached the route limit.", om)
+	for _, c := range counts {
+		writeMetric(w, "goapimon_routes_dropped_total", labels{{"method", c.Method}, {"direction", c.Direction()}}, c.Dropped)
+	}
+}
+
+// writeHistogram emits cumulative buckets, sum and count of one route.
+// Buckets carry their latest exemplar in OpenMetrics mode.
+// Long-lived requests are not observed, so the count may be lower than goapimon_requests_total.
+func writeHistogram(w io.Writer, name string, base labels, bounds []float64, s *model.RouteStats, om bool) {
+	observed := s.TotalCount - s.TotalLongLived
+	cumulative := 0
+	for i := 0; i <= len(bounds); i++ {
+		le := "+Inf"
+		if i < len(bounds) {
+			le = formatFloat(bounds[i])
+		}
+		if i < len(s.TotalHistogram) {
+			cumulative += s.TotalHistogram[i]
+		}
+		if i == len(bounds) {
+			cumulative = observed
+		}
+
+		line := name + "_bucket" + formatLabels(base.with("le", le)) + " " + strconv.Itoa(cumulative)
+		if om && i < len(s.TotalExemplars) && s.TotalExemplars[i].TraceID != "" {
+			line += formatExemplar(s.TotalExemplars[i])
+		}
+		fmt.Fprintln(w, line)
+	}
+	writeMetric(w, name+"_sum", base, formatFloat(s.TotalTime.Seconds()))
+	writeMetric(w, name+"_count", base, observed)
+}
+
+// formatExemplar renders " # {trace_id="..."} value timestamp".
+func formatExemplar(e model.Exemplar) string {
+	ts := float64(e.Timestamp.UnixNano()) / 1e9
+	return " # " + formatLabels(labels{{"trace_id", e.TraceID}}) + " " + formatFloat(e.Value) + " " + strconv.FormatFloat(ts, 'f', 3, 64)
+}
+
+// writeFamily writes HELP and TYPE metadata of a metric family.
+// OpenMetrics names counter families without the _total suffix of their samples.
+func writeFamily(w io.Writer, name, typ, help string, om bool) {
+	if om && typ == "counter" {
+		name = strings.TrimSuffix(name, "_total")
+	}
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+// labels keep their order, so series are written deterministically.
+type labels []label
+
+// with returns a copy of l with one more label appended.
+func (l labels) with(name, value string) labels {
+	res := make(labels, len(l), len(l)+1)
+	copy(res, l)
+	return append(res, label{name, value})
+}
+
+// formatLabels builds Prometheus label block.
+func formatLabels(l labels) string {
+	if len(l) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("{")
+	for i, lb := range l {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(lb.name)
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(lb.value))
+		b.WriteString(`"`)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// writeMetric writes a single metric line.
+func writeMetric(w io.Writer, name string, l labels, value interface{}) {
+	fmt.Fprintf(w, "%s%s %v\n", name, formatLabels(l), value)
+}
@@ -6,28 +6,61 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/aurieli333/goapimon/auth"
 	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/store"
 	"github.com/aurieli333/goapimon/utility"
 )
 
+// Format selects which metrics the exporter writes.
+type Format int
+
+const (
+	// FormatLegacy writes pre-computed windowed gauges such as goapimon_p95_ms{window="1m"}.
+	// It is the default, so existing dashboards and alerts keep working.
+	FormatLegacy Format = iota
+	// FormatStandard writes lifetime counters and a latency histogram with HELP/TYPE metadata,
+	// windows are left to PromQL (rate, histogram_quantile).
+	FormatStandard
+)
+
+const (
+	textContentType        = "text/plain; version=0.0.4; charset=utf-8"
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// acceptsOpenMetrics reports whether the scraper asked for OpenMetrics.
+func acceptsOpenMetrics(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, _ := strings.Cut(part, ";")
+			if strings.TrimSpace(mediaType) == "application/openmetrics-text" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Prometheus exposes metrics in plain text for Prometheus scrapes.
 type Prometheus struct {
-	Mu      *sync.Mutex
+	Store   *store.Store
 	Windows []model.Window
-	Stats   map[string]map[string]*model.RouteStats
+	Format  Format
 
 	Enabled bool
 	Path    string
+
+	// Auth — access rules for scrapes, nil allows everyone
+	Auth *auth.Guard
 }
 
-func NewPrometheus(mu *sync.Mutex, windows []model.Window, stats map[string]map[string]*model.RouteStats) *Prometheus {
+func NewPrometheus(s *store.Store, windows []model.Window) *Prometheus {
 	return &Prometheus{
-		Mu:      mu,
+		Store:   s,
 		Windows: windows,
-		Stats:   stats,
 	}
 }
 
@@ -43,48 +76,81 @@ func (p *Prometheus) Handler() http.HandlerFunc {
 			http.NotFound(w, r)
 			return
 		}
+		if !p.Auth.Check(w, r) {
+			return
+		}
+
+		// Legacy gauges are not valid OpenMetrics, they are always served as plain text
+		om := p.Format == FormatStandard && acceptsOpenMetrics(r)
 
 		// Set content type for Prometheus
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if om {
+			w.Header().Set("Content-Type", openMetricsContentType)
+		} else {
+			w.Header().Set("Content-Type", textContentType)
+		}
 		w.WriteHeader(http.StatusOK)
 
-		// Copy stats under lock so we can release mutex during heavy computation
-		p.Mu.Lock()
-		statsCopy := deepCopyStats(p.Stats)
-		windowsCopy := append([]model.Window(nil), p.Windows...)
-		p.Mu.Unlock()
-
-		now := time.Now()
-
-		// Iterate copied stats and write metrics
-		for method, paths := range statsCopy {
-			for path, s := range paths {
-				// Windowed metrics (per configured windows)
-				for _, win := range windowsCopy {
-					ws := utility.CalcWindowStats(s.Recent, win.Length, now)
-					writeMetrics(w, win.Name, method, path, ws)
-				}
-
-				// Total / lifetime metrics
-				total := calcTotalStats(s)
-				writeMetrics(w, "total", method, path, total)
-			}
+		// Snapshot is a private copy, heavy computation below runs without locks
+		routes := sortedRoutes(p.Store.Snapshot())
+
+		outbound := sortedRoutes(p.Store.OutboundSnapshot())
+		if p.Format == FormatLegacy {
+			p.writeLegacy(w, routes, outbound, p.Store.RouteCounts())
+			return
+		}
+		writeStandard(w, routes, outbound, p.Store.RouteCounts(), p.Store.HistogramBuckets(), om)
+	}
+}
+
+// writeLegacy emits windowed and total gauges for every served route and outbound call,
+// followed by the route limit metrics, which are the same in both formats.
+func (p *Prometheus) writeLegacy(w io.Writer, routes, outbound []route, counts []store.RouteCount) {
+	windowsCopy := append([]model.Window(nil), p.Windows...)
+	now := time.Now()
+
+	for _, rt := range routes {
+		// Windowed metrics (per configured windows)
+		for _, win := range windowsCopy {
+			ws := utility.CalcWindowStats(rt.stats.Buckets, win.Length, now)
+			writeMetrics(w, win.Name, rt.method, rt.path, ws)
 		}
+
+		// Total / lifetime metrics
+		total := calcTotalStats(rt.stats)
+		writeMetrics(w, "total", rt.method, rt.path, total)
 	}
+
+	for _, rt := range outbound {
+		host, path := model.SplitOutboundPath(rt.path)
+		for _, win := range windowsCopy {
+			ws := utility.CalcWindowStats(rt.stats.Buckets, win.Length, now)
+			writeOutboundMetrics(w, win.Name, rt.method, host, path, ws)
+		}
+		writeOutboundMetrics(w, "total", rt.method, host, path, calcTotalStats(rt.stats))
+	}
+
+	writeRouteCounts(w, counts, false)
 }
 
 // writeMetrics emits all metrics for a given (window, method, path) using WindowStats.
 func writeMetrics(w io.Writer, window, method, path string, m utility.WindowStats) {
-	labelsBase := map[string]string{
-		"window": window,
-		"method": method,
-		"path":   path,
+	labelsBase := labels{
+		{"window", window},
+		{"method", method},
+		{"path", path},
 	}
 
-	// status counts
-	for code, cnt := range m.Status {
-		labels := withExtra(labelsBase, map[string]string{"code": strconv.Itoa(code)})
-		writeMetric(w, "goapimon_http_status_total", labels, cnt)
+	// status counts, gRPC routes report their own codes
+	if m.GRPCStatus != nil {
+		for _, code := range sortedNames(m.GRPCStatus) {
+			writeMetric(w, "goapimon_grpc_status_total", labelsBase.with("grpc_code", code), m.GRPCStatus[code])
+		}
+	} else {
+		for _, code := range sortedCodes(m.Status) {
+			labels := labelsBase.with("code", strconv.Itoa(code))
+			writeMetric(w, "goapimon_http_status_total", labels, m.Status[code])
+		}
 	}
 
 	// counters and gauges
@@ -99,6 +165,39 @@ func writeMetrics(w io.Writer, window, method, path string, m utility.WindowStat
 	writeMetric(w, "goapimon_p95_ms", labelsBase, fmt.Sprintf("%.1f", m.P95))
 	writeMetric(w, "goapimon_p99_ms", labelsBase, fmt.Sprintf("%.1f", m.P99))
 	writeMetric(w, "goapimon_throughput_rps", labelsBase, fmt.Sprintf("%.2f", m.RPS))
+	writeMetric(w, "goapimon_avg_request_bytes", labelsBase, fmt.Sprintf("%.1f", m.AvgReqBytes))
+	writeMetric(w, "goapimon_avg_response_bytes", labelsBase, fmt.Sprintf("%.1f", m.AvgRespBytes))
+	writeMetric(w, "goapimon_p95_response_bytes", labelsBase, fmt.Sprintf("%.1f", m.P95RespBytes))
+	writeMetric(w, "goapimon_bandwidth_bytes_per_second", labelsBase, fmt.Sprintf("%.2f", m.Bandwidth))
+}
+
+// writeOutboundMetrics emits the gauges of one outbound call for a given (window, method, host, path).
+func writeOutboundMetrics(w io.Writer, window, method, host, path string, m utility.WindowStats) {
+	labelsBase := labels{
+		{"window", window},
+		{"method", method},
+		{"host", host},
+		{"path", path},
+	}
+
+	for _, code := range sortedCodes(m.Status) {
+		writeMetric(w, "goapimon_outbound_http_status_total", labelsBase.with("code", strconv.Itoa(code)), m.Status[code])
+	}
+	for _, kind := range sortedNames(m.TransportErrors) {
+		writeMetric(w, "goapimon_outbound_transport_errors_total", labelsBase.with("error", kind), m.TransportErrors[kind])
+	}
+
+	writeMetric(w, "goapimon_outbound_requests_total", labelsBase, m.Count)
+	writeMetric(w, "goapimon_outbound_errors_total", labelsBase, m.ErrCount)
+	writeMetric(w, "goapimon_outbound_error_rate", labelsBase, fmt.Sprintf("%.2f", m.ErrorRate))
+	writeMetric(w, "goapimon_outbound_avg_ms", labelsBase, fmt.Sprintf("%.1f", m.Avg))
+	writeMetric(w, "goapimon_outbound_min_ms", labelsBase, fmt.Sprintf("%.1f", m.Min))
+	writeMetric(w, "goapimon_outbound_max_ms", labelsBase, fmt.Sprintf("%.1f", m.Max))
+	writeMetric(w, "goapimon_outbound_p50_ms", labelsBase, fmt.Sprintf("%.1f", m.P50))
+	writeMetric(w, "goapimon_outbound_p90_ms", labelsBase, fmt.Sprintf("%.1f", m.P90))
+	writeMetric(w, "goapimon_outbound_p95_ms", labelsBase, fmt.Sprintf("%.1f", m.P95))
+	writeMetric(w, "goapimon_outbound_p99_ms", labelsBase, fmt.Sprintf("%.1f", m.P99))
+	writeMetric(w, "goapimon_outbound_throughput_rps", labelsBase, fmt.Sprintf("%.2f", m.RPS))
 }
 
 // calcTotalStats builds WindowStats from RouteStats aggregates (lifetime metrics).
@@ -109,83 +208,57 @@ func calcTotalStats(s *model.RouteStats) utility.WindowStats {
 		rps = float64(s.TotalCount) / duration
 	}
 
+	var bandwidth float64
+	if duration > 0 {
+		bandwidth = float64(s.TotalReqBytes+s.TotalRespBytes) / duration
+	}
+
 	var errorRate float64
 	if s.TotalCount > 0 {
 		errorRate = float64(s.TotalErrorCount) / float64(s.TotalCount) * 100
 	}
 
+	p50, p90, p95, p99 := utility.Quantiles(s.TotalLatency)
+	timed := s.TotalCount - s.TotalLongLived
+	minMs, maxMs := float64(-1), float64(-1)
+	if timed > 0 {
+		minMs = float64(s.TotalMin.Milliseconds())
+		maxMs = float64(s.TotalMax.Milliseconds())
+	}
+
 	return utility.WindowStats{
 		Count:     s.TotalCount,
 		ErrCount:  s.TotalErrorCount,
 		ErrorRate: errorRate,
 		Status:    s.TotalStatus,
-		Avg:       msSafeDiv(s.TotalTime.Milliseconds(), s.TotalCount),
-		Min:       float64(s.TotalMin.Milliseconds()),
-		Max:       float64(s.TotalMax.Milliseconds()),
-		P50:       -1, // can be filled from t-digest stored in RouteStats if added later
-		P90:       -1,
-		P95:       -1,
-		P99:       -1,
+		LongLived: s.TotalLongLived,
+		Avg:       safeDiv(s.TotalTime.Milliseconds(), timed),
+		Min:       minMs,
+		Max:       maxMs,
+		P50:       p50,
+		P90:       p90,
+		P95:       p95,
+		P99:       p99,
 		RPS:       rps,
-	}
-}
 
-func msSafeDiv(ms int64, count int) float64 {
-	if count == 0 {
-		return 0
-	}
-	return float64(ms) / float64(count)
-}
+		ReqBytes:     s.TotalReqBytes,
+		RespBytes:    s.TotalRespBytes,
+		AvgReqBytes:  safeDiv(s.TotalReqBytes, s.TotalCount),
+		AvgRespBytes: safeDiv(s.TotalRespBytes, s.TotalCount),
+		P95RespBytes: -1,
+		Bandwidth:    bandwidth,
 
-// withExtra merges two label maps and returns a new map.
-func withExtra(base, extra map[string]string) map[string]string {
-	res := make(map[string]string, len(base)+len(extra))
-	for k, v := range base {
-		res[k] = v
-	}
-	for k, v := range extra {
-		res[k] = v
-	}
-	return res
-}
+		GRPCStatus:   s.TotalGRPCStatus,
+		MsgsReceived: s.TotalMsgsReceived,
+		MsgsSent:     s.TotalMsgsSent,
 
-// formatLabels builds Prometheus label block from map.
-func formatLabels(labels map[string]string) string {
-	var b strings.Builder
-	b.WriteString("{")
-	first := true
-	for k, v := range labels {
-		if !first {
-			b.WriteString(",")
-		}
-		// Note: labels are not escaped here; if you may have quotes or backslashes, escape them.
-		b.WriteString(fmt.Sprintf("%s=\"%s\"", k, v))
-		first = false
+		TransportErrors: s.TotalTransportErrors,
 	}
-	b.WriteString("}")
-	return b.String()
-}
-
-// writeMetric writes a single metric line.
-func writeMetric(w io.Writer, name string, labels map[string]string, value interface{}) {
-	fmt.Fprintf(w, "%s%s %v\n", name, formatLabels(labels), value)
 }
 
-// deepCopyStats makes a shallow-deep copy of the stats map so we can work without lock.
-// It copies RouteStats struct and creates a new slice for Recent.
-func deepCopyStats(src map[string]map[string]*model.RouteStats) map[string]map[string]*model.RouteStats {
-	out := make(map[string]map[string]*model.RouteStats, len(src))
-	for method, pathStats := range src {
-		out[method] = make(map[string]*model.RouteStats, len(pathStats))
-		for path, stat := range pathStats {
-			// copy struct value
-			newStat := *stat
-			// copy recent slice
-			newRecent := make([]model.RequestRecord, len(stat.Recent))
-			copy(newRecent, stat.Recent)
-			newStat.Recent = newRecent
-			out[method][path] = &newStat
-		}
+func safeDiv(total int64, count int) float64 {
+	if count == 0 {
+		return 0
 	}
-	return out
+	return float64(total) / float64(count)
 }
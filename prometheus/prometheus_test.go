@@ -0,0 +1,196 @@
+package prometheus
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/store"
+
+	"github.com/prometheus/common/expfmt"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/*.golden with the current output")
+
+// oddPath needs every escape of the text format
+const oddPath = `/say/"hi"\there` + "\n" + "next"
+
+// invalidPath is not valid UTF-8, as stored by a request for /files/%ff
+const invalidPath = "/files/\xff"
+
+// testStore holds one route of every kind, recorded long enough ago
+// that no window contains them and the output does not depend on the clock.
+func testStore() *store.Store {
+	s := store.NewStore(store.Options{
+		Retention:        time.Minute,
+		BucketWidth:      10 * time.Second,
+		HistogramBuckets: []float64{.01, .1, 1},
+		MaxRoutes:        2,
+	})
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	at := func(sec int) time.Time { return start.Add(time.Duration(sec) * time.Second) }
+	for _, rec := range []model.RequestRecord{
+		{Timestamp: at(0), Duration: 5 * time.Millisecond, Status: 200, Method: "GET", Path: "/users/:id", TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", RespBytes: 120},
+		{Timestamp: at(1), Duration: 250 * time.Millisecond, Status: 500, Method: "GET", Path: "/users/:id", RespBytes: 20},
+		{Timestamp: at(2), Duration: 40 * time.Millisecond, Status: 201, Method: "POST", Path: oddPath, ReqBytes: 12, RespBytes: 34},
+		{Timestamp: at(2), Duration: 6 * time.Millisecond, Status: 404, Method: "POST", Path: invalidPath},
+		{Timestamp: at(3), Duration: 2 * time.Millisecond, Status: 200, Method: "GET", Path: "/a"},
+		{Timestamp: at(4), Duration: 3 * time.Millisecond, Status: 404, Method: "GET", Path: "/b"},
+		{Timestamp: at(5), Duration: 3 * time.Second, Status: 200, Method: "GRPC", Path: "/chat.Chat/Talk", GRPCCode: "OK", MsgsReceived: 3, MsgsSent: 4, LongLived: true},
+		{Timestamp: at(6), Duration: 8 * time.Millisecond, Status: 503, Method: "GRPC", Path: "/chat.Chat/Send", GRPCCode: "Unavailable"},
+		{Timestamp: at(7), Duration: 80 * time.Millisecond, Status: 503, Method: "GET", Path: model.OutboundPath("api.example.com", "/v1/items"), Outbound: true},
+		{Timestamp: at(8), Duration: time.Second, Method: "POST", Path: model.OutboundPath("db.internal:5432", "/"), TransportError: "timeout", Outbound: true},
+	} {
+		s.Record(rec)
+	}
+	return s
+}
+
+func scrape(t *testing.T, format Format, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	p := NewPrometheus(testStore(), []model.Window{{Name: "1m", Length: time.Minute}})
+	p.Format = format
+	p.Enable("/metrics")
+
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	p.Handler().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	return w
+}
+
+func TestExposition(t *testing.T) {
+	for _, tt := range []struct {
+		golden      string
+		format      Format
+		accept      string
+		contentType string
+	}{
+		{"legacy", FormatLegacy, "", textContentType},
+		{"legacy_openmetrics_accept", FormatLegacy, "application/openmetrics-text; version=1.0.0", textContentType},
+		{"standard", FormatStandard, "", textContentType},
+		{"standard_openmetrics", FormatStandard, "application/openmetrics-text; version=1.0.0,text/plain;q=0.5", openMetricsContentType},
+	} {
+		t.Run(tt.golden, func(t *testing.T) {
+			w := scrape(t, tt.format, tt.accept)
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("Content-Type %q, want %q", ct, tt.contentType)
+			}
+
+			golden := tt.golden
+			if golden == "legacy_openmetrics_accept" {
+				golden = "legacy" // legacy gauges are never served as OpenMetrics
+			}
+			checkGolden(t, filepath.Join("testdata", golden+".golden"), w.Body.Bytes())
+
+			if tt.contentType == textContentType {
+				parseText(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run go test -update to create it", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s, run go test -update if the change is intended:\n%s", path, got)
+	}
+}
+
+// parseText validates body with the Prometheus text format parser
+// and checks that escaped label values read back unchanged.
+func parseText(t *testing.T, body []byte) {
+	t.Helper()
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("text format parser: %v", err)
+	}
+
+	found := map[string]bool{}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			for _, lp := range m.GetLabel() {
+				if lp.GetName() == "path" {
+					found[lp.GetValue()] = true
+				}
+			}
+		}
+	}
+	for _, path := range []string{oddPath, "/files/\uFFFD"} {
+		if !found[path] {
+			t.Errorf("no series with path label %q after parsing", path)
+		}
+	}
+}
+
+func TestStandardFamilies(t *testing.T) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(scrape(t, FormatStandard, "").Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, typ := range map[string]string{
+		"goapimon_requests_total":                    "COUNTER",
+		"goapimon_grpc_requests_total":               "COUNTER",
+		"goapimon_request_duration_seconds":          "HISTOGRAM",
+		"goapimon_outbound_request_duration_seconds": "HISTOGRAM",
+		"goapimon_outbound_transport_errors_total":   "COUNTER",
+		"goapimon_routes":                            "GAUGE",
+		"goapimon_routes_dropped_total":              "COUNTER",
+	} {
+		mf, ok := families[name]
+		if !ok {
+			t.Errorf("missing family %s", name)
+			continue
+		}
+		if got := mf.GetType().String(); got != typ {
+			t.Errorf("%s: type %s, want %s", name, got, typ)
+		}
+		if mf.GetHelp() == "" {
+			t.Errorf("%s: no HELP", name)
+		}
+	}
+
+	// /users/:id has a 5ms and a 250ms request, the +Inf bucket equals the count
+	for _, m := range families["goapimon_request_duration_seconds"].GetMetric() {
+		labels := map[string]string{}
+		for _, lp := range m.GetLabel() {
+			labels[lp.GetName()] = lp.GetValue()
+		}
+		if labels["path"] != "/users/:id" {
+			continue
+		}
+		h := m.GetHistogram()
+		want := []uint64{1, 1, 2, 2}
+		for i, b := range h.GetBucket() {
+			if b.GetCumulativeCount() != want[i] {
+				t.Errorf("bucket le=%v: %d, want %d", b.GetUpperBound(), b.GetCumulativeCount(), want[i])
+			}
+		}
+		if h.GetSampleCount() != 2 {
+			t.Errorf("count %d, want 2", h.GetSampleCount())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"slices"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+
+	"github.com/influxdata/tdigest"
+)
+
+// addHistory puts a request completed at now into its history point.
+// Only slots that saw requests get a point, so idle routes stay small.
+// The newest point is the live one, its percentiles come from r.histLive.
+func (r *route) addHistory(opts Options, now time.Time, rec model.RequestRecord) {
+	slot := now.Truncate(opts.HistoryInterval)
+	ms := float64(rec.Duration.Nanoseconds()) / 1_000_000.
+	n := len(r.history)
+
+	var p *model.HistoryPoint
+	switch {
+	case n > 0 && r.history[n-1].Start.Equal(slot):
+		p = &r.history[n-1]
+		if !rec.LongLived {
+			r.histLive.Add(ms, 1)
+		}
+	case n == 0 || slot.After(r.history[n-1].Start):
+		r.closeHistory()
+		r.trimHistory(slot.Add(-opts.HistoryRetention))
+		r.history = append(r.history, model.HistoryPoint{Start: slot})
+		p = &r.history[len(r.history)-1]
+		if !rec.LongLived {
+			r.histLive.Add(ms, 1)
+		}
+	case slot.Before(r.history[n-1].Start.Add(-opts.HistoryRetention)):
+		// Older than anything still kept
+		return
+	default:
+		// Late request, percentiles of closed points stay as they were
+		i, found := slices.BinarySearchFunc(r.history, slot, func(p model.HistoryPoint, t time.Time) int {
+			return p.Start.Compare(t)
+		})
+		if !found {
+			r.history = slices.Insert(r.history, i, model.HistoryPoint{Start: slot, P50: ms, P95: ms, P99: ms})
+		}
+		p = &r.history[i]
+	}
+
+	p.Count++
+	if rec.LongLived {
+		p.LongLived++
+	} else {
+		p.Sum += rec.Duration
+		p.Max = max(p.Max, rec.Duration)
+	}
+	switch {
+	case rec.TransportError != "":
+		p.Classes[0]++
+		p.ErrCount++
+	case rec.Status >= 100 && rec.Status < 600:
+		p.Classes[rec.Status/100]++
+		if rec.Status >= 400 {
+			p.ErrCount++
+		}
+	default:
+		p.Classes[0]++
+	}
+}
+
+// closeHistory fixes the percentiles of the live point before a newer one starts.
+func (r *route) closeHistory() {
+	if len(r.history) == 0 || r.histLive.Count() == 0 {
+		return
+	}
+	p := &r.history[len(r.history)-1]
+	p.P50, p.P95, p.P99 = quantiles(r.histLive)
+	r.histLive.Reset()
+}
+
+// trimHistory drops points that started before cutoff.
+func (r *route) trimHistory(cutoff time.Time) {
+	i := 0
+	for i < len(r.history) && r.history[i].Start.Before(cutoff) {
+		i++
+	}
+	r.history = r.history[i:]
+}
+
+// historySnapshot copies the points that started at or after cutoff.
+func (r *route) historySnapshot(cutoff time.Time) []model.HistoryPoint {
+	i, _ := slices.BinarySearchFunc(r.history, cutoff, func(p model.HistoryPoint, t time.Time) int {
+		return p.Start.Compare(t)
+	})
+	out := slices.Clone(r.history[i:])
+	if len(out) > 0 && len(out) == len(r.history)-i && r.histLive.Count() > 0 {
+		last := &out[len(out)-1]
+		last.P50, last.P95, last.P99 = quantiles(r.histLive)
+	}
+	return out
+}
+
+func quantiles(td *tdigest.TDigest) (p50, p95, p99 float64) {
+	return td.Quantile(0.5), td.Quantile(0.95), td.Quantile(0.99)
+}
+
+// History returns the points of one route in the last HistoryRetention, oldest first.
+// Slots without requests have no point. ok is false for unknown routes
+// and when history is disabled.
+func (s *Store) History(outbound bool, method, path string) (points []model.HistoryPoint, ok bool) {
+	if s.opts.HistoryRetention <= 0 {
+		return nil, false
+	}
+	key := routeKey{outbound: outbound, method: method, path: path}
+	sh := s.shardFor(key)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	r, ok := sh.routes[key]
+	if !ok {
+		return nil, false
+	}
+	cutoff := time.Now().Truncate(s.opts.HistoryInterval).Add(-s.opts.HistoryRetention)
+	return r.historySnapshot(cutoff), true
+}
+
+// HistoryInterval returns the time slot aggregated into one history point.
+func (s *Store) HistoryInterval() time.Duration {
+	return s.opts.HistoryInterval
+}
+
+// HistoryRetention returns how long history points are kept, 0 when history is disabled.
+func (s *Store) HistoryRetention() time.Duration {
+	return max(s.opts.HistoryRetention, 0)
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+)
+
+// addSample keeps rec when it is among the slowest requests still in retention
+// or when it failed. Both lists hold at most opts.Samples entries.
+func (r *route) addSample(opts Options, rec model.RequestRecord) {
+	sample := model.Sample{
+		Timestamp:      rec.Timestamp,
+		Duration:       rec.Duration,
+		Status:         rec.Status,
+		GRPCCode:       rec.GRPCCode,
+		TransportError: rec.TransportError,
+		Client:         rec.Client,
+		TraceID:        rec.TraceID,
+	}
+
+	if rec.TransportError != "" || rec.Status >= 400 {
+		if len(r.errors) < opts.Samples {
+			r.errors = append(r.errors, sample)
+		} else {
+			r.errors[r.errorsNext] = sample
+		}
+		r.errorsNext = (r.errorsNext + 1) % opts.Samples
+	}
+
+	if rec.LongLived {
+		return
+	}
+	if len(r.slowest) < opts.Samples {
+		r.slowest = append(r.slowest, sample)
+		return
+	}
+	// Replace an expired sample first, otherwise the fastest one if rec is slower
+	cutoff := rec.Timestamp.Add(-opts.Retention)
+	victim := 0
+	for i, s := range r.slowest {
+		if s.Timestamp.Before(cutoff) {
+			victim = i
+			break
+		}
+		if s.Duration < r.slowest[victim].Duration {
+			victim = i
+		}
+	}
+	if r.slowest[victim].Timestamp.Before(cutoff) || r.slowest[victim].Duration < rec.Duration {
+		r.slowest[victim] = sample
+	}
+}
+
+// Samples returns the slowest requests of one route in the last Retention, slowest first,
+// and its latest failed requests, newest first. ok is false for unknown routes.
+func (s *Store) Samples(outbound bool, method, path string) (slowest, errors []model.Sample, ok bool) {
+	key := routeKey{outbound: outbound, method: method, path: path}
+	sh := s.shardFor(key)
+
+	sh.mu.Lock()
+	r, ok := sh.routes[key]
+	if ok {
+		slowest = slices.Clone(r.slowest)
+		errors = slices.Clone(r.errors)
+	}
+	sh.mu.Unlock()
+	if !ok {
+		return nil, nil, false
+	}
+
+	cutoff := time.Now().Add(-s.opts.Retention)
+	slowest = slices.DeleteFunc(slowest, func(s model.Sample) bool {
+		return s.Timestamp.Before(cutoff)
+	})
+	slices.SortFunc(slowest, func(a, b model.Sample) int {
+		return cmp.Compare(b.Duration, a.Duration)
+	})
+	slices.SortFunc(errors, func(a, b model.Sample) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+	return slowest, errors, true
+}
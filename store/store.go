@@ -0,0 +1,513 @@
+package store
+
+import (
+	"hash/maphash"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+
+	"github.com/influxdata/tdigest"
+)
+
+// shardCount must be a power of two
+const shardCount = 64
+
+// Compression used by per-bucket latency digests.
+// Lower than the dashboard's 1000 to keep closed buckets small.
+const digestCompression = 100
+
+// Compression of the lifetime latency digest, its size stays bounded by this value.
+const totalDigestCompression = 200
+
+type routeKey struct {
+	outbound bool
+	method   string
+	path     string
+}
+
+// route is the mutable state of one method + path.
+// Buckets form a ring indexed by time slot, so memory does not grow with traffic.
+type route struct {
+	stats    model.RouteStats // lifetime aggregates
+	buckets  []model.Bucket
+	live     *tdigest.TDigest // latency digest of the newest bucket
+	liveSize *tdigest.TDigest // response size digest of the newest bucket
+	liveIdx  int
+	total    *tdigest.TDigest // lifetime latency digest
+
+	history  []model.HistoryPoint // downsampled points, oldest first
+	histLive *tdigest.TDigest     // latency digest of the newest history point
+
+	slowest    []model.Sample // slowest requests, unordered
+	errors     []model.Sample // ring of the latest failed requests
+	errorsNext int            // next slot of errors to overwrite
+}
+
+func newRoute(opts Options, start time.Time) *route {
+	return &route{
+		stats: model.RouteStats{
+			TotalStatus:    make(map[int]int),
+			TotalHistogram: make([]int, len(opts.HistogramBuckets)+1),
+			TotalExemplars: make([]model.Exemplar, len(opts.HistogramBuckets)+1),
+			FirstSeen:      start,
+		},
+		buckets:  make([]model.Bucket, bucketCount(opts)),
+		live:     tdigest.NewWithCompression(digestCompression),
+		liveSize: tdigest.NewWithCompression(digestCompression),
+		liveIdx:  -1,
+		total:    tdigest.NewWithCompression(totalDigestCompression),
+		histLive: tdigest.NewWithCompression(digestCompression),
+	}
+}
+
+// add puts a request completed at now into its time bucket.
+func (r *route) add(width time.Duration, now time.Time, rec model.RequestRecord) {
+	slot := now.Truncate(width)
+	// Folded into the ring, times before 1970 give a negative remainder
+	n := len(r.buckets)
+	idx := (int(slot.UnixNano()/int64(width))%n + n) % n
+	b := &r.buckets[idx]
+	elapsed, status := rec.Duration, rec.Status
+	ms := float64(elapsed.Nanoseconds()) / 1_000_000.
+	size := float64(rec.RespBytes)
+
+	switch {
+	case idx == r.liveIdx && b.Start.Equal(slot):
+		r.liveSize.Add(size, 1)
+		if !rec.LongLived {
+			r.live.Add(ms, 1)
+		}
+	case !b.End.IsZero() && b.Start.Equal(slot):
+		// Late request for an already closed bucket, unused buckets have no End
+		b.RespSize = append(b.RespSize, tdigest.Centroid{Mean: size, Weight: 1})
+		if !rec.LongLived {
+			b.Latency = append(b.Latency, tdigest.Centroid{Mean: ms, Weight: 1})
+		}
+	case r.liveIdx >= 0 && slot.Before(r.buckets[r.liveIdx].Start):
+		// Older than anything still kept in the ring
+		return
+	default:
+		r.rotate(idx, slot, width)
+		r.liveSize.Add(size, 1)
+		if !rec.LongLived {
+			r.live.Add(ms, 1)
+		}
+	}
+
+	if rec.LongLived {
+		b.LongLived++
+	} else {
+		if b.Count == b.LongLived || elapsed < b.Min {
+			b.Min = elapsed
+		}
+		if elapsed > b.Max {
+			b.Max = elapsed
+		}
+		b.Sum += elapsed
+	}
+	b.Count++
+	b.ReqBytes += rec.ReqBytes
+	b.RespBytes += rec.RespBytes
+	if rec.TransportError != "" {
+		if b.TransportErrors == nil {
+			b.TransportErrors = make(map[string]int)
+		}
+		b.TransportErrors[rec.TransportError]++
+		b.ErrCount++
+	} else {
+		b.Status[status]++
+		if status >= 400 {
+			b.ErrCount++
+		}
+	}
+	if rec.GRPCCode != "" {
+		if b.GRPCStatus == nil {
+			b.GRPCStatus = make(map[string]int)
+		}
+		b.GRPCStatus[rec.GRPCCode]++
+		b.MsgsReceived += rec.MsgsReceived
+		b.MsgsSent += rec.MsgsSent
+	}
+}
+
+// rotate closes the live bucket and reuses the slot idx for a new one.
+func (r *route) rotate(idx int, slot time.Time, width time.Duration) {
+	if r.liveIdx >= 0 {
+		r.buckets[r.liveIdx].Latency = r.live.Centroids()
+		r.buckets[r.liveIdx].RespSize = r.liveSize.Centroids()
+		r.live.Reset()
+		r.liveSize.Reset()
+	}
+
+	b := &r.buckets[idx]
+	status := b.Status
+	if status == nil {
+		status = make(map[int]int)
+	}
+	clear(status)
+	grpcStatus := b.GRPCStatus
+	clear(grpcStatus)
+	transportErrors := b.TransportErrors
+	clear(transportErrors)
+	*b = model.Bucket{
+		Start:           slot,
+		End:             slot.Add(width),
+		Status:          status,
+		GRPCStatus:      grpcStatus,
+		TransportErrors: transportErrors,
+	}
+	r.liveIdx = idx
+}
+
+// snapshot copies the aggregates and the buckets still in retention.
+func (r *route) snapshot(retention time.Duration, now time.Time) *model.RouteStats {
+	c := r.stats
+	c.TotalStatus = make(map[int]int, len(r.stats.TotalStatus))
+	for code, cnt := range r.stats.TotalStatus {
+		c.TotalStatus[code] = cnt
+	}
+	c.TotalGRPCStatus = maps.Clone(r.stats.TotalGRPCStatus)
+	c.TotalTransportErrors = maps.Clone(r.stats.TotalTransportErrors)
+	c.TotalLatency = r.total.Centroids()
+	c.TotalHistogram = slices.Clone(r.stats.TotalHistogram)
+	c.TotalExemplars = slices.Clone(r.stats.TotalExemplars)
+
+	cutoff := now.Add(-retention)
+	c.Buckets = make([]model.Bucket, 0, len(r.buckets))
+	for i := range r.buckets {
+		b := r.buckets[i]
+		if b.Count == 0 || !b.End.After(cutoff) {
+			continue
+		}
+		b.Status = make(map[int]int, len(r.buckets[i].Status))
+		for code, cnt := range r.buckets[i].Status {
+			b.Status[code] = cnt
+		}
+		b.GRPCStatus = maps.Clone(b.GRPCStatus)
+		b.TransportErrors = maps.Clone(b.TransportErrors)
+		if i == r.liveIdx {
+			b.Latency = r.live.Centroids()
+			b.RespSize = r.liveSize.Centroids()
+		} else {
+			b.Latency = slices.Clone(b.Latency)
+			b.RespSize = slices.Clone(b.RespSize)
+		}
+		c.Buckets = append(c.Buckets, b)
+	}
+	sort.Slice(c.Buckets, func(i, j int) bool {
+		return c.Buckets[i].Start.Before(c.Buckets[j].Start)
+	})
+	return &c
+}
+
+// shard holds a subset of routes behind its own mutex,
+// so requests to different routes rarely contend.
+type shard struct {
+	mu     sync.Mutex
+	routes map[routeKey]*route
+}
+
+// DefaultHistogramBuckets are latency histogram upper bounds in seconds.
+var DefaultHistogramBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Options configures how much windowed data a Store keeps.
+type Options struct {
+	Retention   time.Duration // how long buckets are kept
+	BucketWidth time.Duration // time slot aggregated into one bucket
+
+	// HistogramBuckets are ascending latency upper bounds in seconds
+	// for the lifetime histogram. Defaults to DefaultHistogramBuckets.
+	HistogramBuckets []float64
+
+	// MaxRoutes limits distinct paths per method, 0 means unlimited.
+	// Requests to further paths are recorded under model.OtherRoute.
+	MaxRoutes int
+
+	// HistoryRetention — how long downsampled per-route history is kept, 0 disables it.
+	HistoryRetention time.Duration
+	// HistoryInterval — time slot aggregated into one history point, defaults to 10s.
+	HistoryInterval time.Duration
+
+	// Samples — slowest and failed requests kept per route, 0 disables them.
+	Samples int
+}
+
+// RouteCount — number of routes tracked for one method and requests dropped by the route limit
+type RouteCount struct {
+	Outbound bool
+	Method   string
+	Routes   int // distinct paths, not counting model.OtherRoute and model.UnmatchedRoute
+	Dropped  int // requests recorded under model.OtherRoute
+}
+
+// Direction is "outbound" for outbound calls and "inbound" for served requests.
+func (c RouteCount) Direction() string {
+	if c.Outbound {
+		return "outbound"
+	}
+	return "inbound"
+}
+
+type methodKey struct {
+	outbound bool
+	method   string
+}
+
+// bucketCount is the ring size covering retention plus the partially filled bucket.
+func bucketCount(opts Options) int {
+	n := int(opts.Retention / opts.BucketWidth)
+	if opts.Retention%opts.BucketWidth != 0 {
+		n++
+	}
+	return n + 1
+}
+
+// Store owns per-route statistics and their synchronization.
+// Writers go through Record, readers get an independent copy from Snapshot,
+// so the monitor, dashboard and exporter never touch shared maps directly.
+type Store struct {
+	opts   Options
+	seed   maphash.Seed
+	shards [shardCount]shard
+
+	// counts is only touched when a route is created, not on every request
+	countsMu sync.Mutex
+	counts   map[methodKey]*RouteCount
+}
+
+// NewStore creates a Store. Zero options fall back to 5m retention in 10s buckets.
+func NewStore(opts Options) *Store {
+	if opts.Retention <= 0 {
+		opts.Retention = 5 * time.Minute
+	}
+	if opts.BucketWidth <= 0 {
+		opts.BucketWidth = 10 * time.Second
+	}
+	if len(opts.HistogramBuckets) == 0 {
+		opts.HistogramBuckets = DefaultHistogramBuckets
+	}
+	if opts.HistoryInterval <= 0 {
+		opts.HistoryInterval = 10 * time.Second
+	}
+	opts.HistogramBuckets = slices.Clone(opts.HistogramBuckets)
+	s := &Store{opts: opts, seed: maphash.MakeSeed(), counts: make(map[methodKey]*RouteCount)}
+	for i := range s.shards {
+		s.shards[i].routes = make(map[routeKey]*route)
+	}
+	return s
+}
+
+func (s *Store) shardFor(key routeKey) *shard {
+	var h maphash.Hash
+	h.SetSeed(s.seed)
+	h.WriteString(key.method)
+	h.WriteByte(0)
+	h.WriteString(key.path)
+	return &s.shards[h.Sum64()&(shardCount-1)]
+}
+
+// Record adds a single request to the statistics of rec.Method + rec.Path.
+// Outbound calls are kept apart from served requests, see OutboundSnapshot.
+// Once a method reaches Options.MaxRoutes, new paths are recorded under model.OtherRoute.
+// Non-standard methods are recorded under model.OtherMethod.
+// Invalid UTF-8 in the path, e.g. from /files/%ff, is replaced with U+FFFD: label
+// values must be valid UTF-8, or one request would break every scrape.
+func (s *Store) Record(rec model.RequestRecord) {
+	rec.Method = model.GroupMethod(rec.Method)
+	rec.Path = strings.ToValidUTF8(rec.Path, "\uFFFD")
+	rec.TraceID = strings.ToValidUTF8(rec.TraceID, "\uFFFD")
+	key := routeKey{outbound: rec.Outbound, method: rec.Method, path: rec.Path}
+	if !s.record(key, rec, false) {
+		key.path = model.OtherRoute
+		s.record(key, rec, true)
+	}
+}
+
+// record reports false, without recording, when key is a new route over the limit.
+// force creates the route regardless of the limit.
+func (s *Store) record(key routeKey, rec model.RequestRecord, force bool) bool {
+	start, status := rec.Timestamp, rec.Status
+	now := start.Add(rec.Duration)
+	sh := s.shardFor(key)
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	r, ok := sh.routes[key]
+	if !ok {
+		if !force && !s.admit(key) {
+			return false
+		}
+		r = newRoute(s.opts, start)
+		sh.routes[key] = r
+	}
+
+	// add new data
+	r.add(s.opts.BucketWidth, now, rec)
+	if s.opts.HistoryRetention > 0 {
+		r.addHistory(s.opts, now, rec)
+	}
+	if s.opts.Samples > 0 {
+		r.addSample(s.opts, rec)
+	}
+
+	// Refresh aggregates
+	rs := &r.stats
+	rs.TotalCount++
+	rs.TotalReqBytes += rec.ReqBytes
+	rs.TotalRespBytes += rec.RespBytes
+	if rec.LongLived {
+		rs.TotalLongLived++
+	} else {
+		s.recordLatency(rs, r.total, rec, now)
+	}
+	rs.LastSeen = now
+	if rec.TransportError != "" {
+		if rs.TotalTransportErrors == nil {
+			rs.TotalTransportErrors = make(map[string]int)
+		}
+		rs.TotalTransportErrors[rec.TransportError]++
+		rs.TotalErrorCount++
+	} else {
+		rs.TotalStatus[status]++
+		if status >= 400 {
+			rs.TotalErrorCount++
+		}
+	}
+	if rec.GRPCCode != "" {
+		if rs.TotalGRPCStatus == nil {
+			rs.TotalGRPCStatus = make(map[string]int)
+		}
+		rs.TotalGRPCStatus[rec.GRPCCode]++
+		rs.TotalMsgsReceived += rec.MsgsReceived
+		rs.TotalMsgsSent += rec.MsgsSent
+	}
+	return true
+}
+
+// admit counts a new route of key.method, or the dropped request when the limit is reached.
+// The grouping routes model.OtherRoute and model.UnmatchedRoute never count towards the limit.
+func (s *Store) admit(key routeKey) bool {
+	s.countsMu.Lock()
+	defer s.countsMu.Unlock()
+
+	mk := methodKey{outbound: key.outbound, method: key.method}
+	c, ok := s.counts[mk]
+	if !ok {
+		c = &RouteCount{Outbound: key.outbound, Method: key.method}
+		s.counts[mk] = c
+	}
+	if key.path == model.OtherRoute || key.path == model.UnmatchedRoute {
+		return true
+	}
+	if s.opts.MaxRoutes > 0 && c.Routes >= s.opts.MaxRoutes {
+		c.Dropped++
+		return false
+	}
+	c.Routes++
+	return true
+}
+
+// RouteCounts returns tracked routes and dropped requests per method, ordered by method.
+func (s *Store) RouteCounts() []RouteCount {
+	s.countsMu.Lock()
+	out := make([]RouteCount, 0, len(s.counts))
+	for _, c := range s.counts {
+		out = append(out, *c)
+	}
+	s.countsMu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Outbound != out[j].Outbound {
+			return !out[i].Outbound
+		}
+		return out[i].Method < out[j].Method
+	})
+	return out
+}
+
+// recordLatency updates lifetime latency aggregates, histogram and exemplars.
+func (s *Store) recordLatency(rs *model.RouteStats, total *tdigest.TDigest, rec model.RequestRecord, now time.Time) {
+	elapsed := rec.Duration
+	if rs.TotalCount-rs.TotalLongLived == 1 || elapsed < rs.TotalMin {
+		rs.TotalMin = elapsed
+	}
+	if elapsed > rs.TotalMax {
+		rs.TotalMax = elapsed
+	}
+	rs.TotalTime += elapsed
+	total.Add(float64(elapsed.Nanoseconds())/1_000_000., 1)
+
+	hb := sort.SearchFloat64s(s.opts.HistogramBuckets, elapsed.Seconds())
+	rs.TotalHistogram[hb]++
+	if rec.TraceID != "" {
+		rs.TotalExemplars[hb] = model.Exemplar{
+			TraceID:   rec.TraceID,
+			Value:     elapsed.Seconds(),
+			Timestamp: now,
+		}
+	}
+}
+
+// Snapshot returns a deep copy of the statistics of all served routes.
+// Every route is copied atomically; shards are visited one by one,
+// so recording is never blocked for longer than one shard copy.
+// The copy is owned by the caller and can be read without any locking.
+func (s *Store) Snapshot() map[string]map[string]*model.RouteStats {
+	return s.snapshot(false)
+}
+
+// OutboundSnapshot is Snapshot for outbound calls, paths are built by model.OutboundPath.
+func (s *Store) OutboundSnapshot() map[string]map[string]*model.RouteStats {
+	return s.snapshot(true)
+}
+
+func (s *Store) snapshot(outbound bool) map[string]map[string]*model.RouteStats {
+	now := time.Now()
+	out := make(map[string]map[string]*model.RouteStats)
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		for key, r := range sh.routes {
+			if key.outbound != outbound {
+				continue
+			}
+			pathStats, ok := out[key.method]
+			if !ok {
+				pathStats = make(map[string]*model.RouteStats)
+				out[key.method] = pathStats
+			}
+			pathStats[key.path] = r.snapshot(s.opts.Retention, now)
+		}
+		sh.mu.Unlock()
+	}
+	return out
+}
+
+// Retention returns how long windowed data is kept.
+func (s *Store) Retention() time.Duration {
+	return s.opts.Retention
+}
+
+// HistogramBuckets returns the latency histogram upper bounds in seconds.
+func (s *Store) HistogramBuckets() []float64 {
+	return slices.Clone(s.opts.HistogramBuckets)
+}
+
+// Reset drops all collected statistics.
+func (s *Store) Reset() {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		sh.routes = make(map[routeKey]*route)
+		sh.mu.Unlock()
+	}
+	s.countsMu.Lock()
+	s.counts = make(map[methodKey]*RouteCount)
+	s.countsMu.Unlock()
+}
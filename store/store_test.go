@@ -0,0 +1,299 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aurieli333/goapimon/model"
+)
+
+// TestConcurrentRecordAndRead hammers Record while every read API runs and
+// scribbles over the copies it gets back. Run with -race: a snapshot sharing
+// a map or slice with the store shows up as a data race.
+func TestConcurrentRecordAndRead(t *testing.T) {
+	s := NewStore(Options{
+		Retention:        time.Minute,
+		BucketWidth:      time.Second,
+		MaxRoutes:        4,
+		HistoryRetention: time.Minute,
+		HistoryInterval:  time.Second,
+		Samples:          5,
+	})
+
+	const writers, perWriter = 8, 400
+	paths := []string{"/a", "/b", "/c", "/d", "/e", "/f"}
+	statuses := []int{200, 201, 404, 500}
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				readAll(s)
+			}
+		}()
+	}
+
+	var writersWG sync.WaitGroup
+	for w := range writers {
+		writersWG.Add(1)
+		go func() {
+			defer writersWG.Done()
+			for i := range perWriter {
+				rec := model.RequestRecord{
+					Timestamp: time.Now(),
+					Duration:  time.Duration(i%50) * time.Millisecond,
+					Status:    statuses[i%len(statuses)],
+					Method:    "GET",
+					Path:      paths[(w+i)%len(paths)],
+					TraceID:   fmt.Sprintf("%032x", i),
+					LongLived: i%25 == 0,
+				}
+				if i%5 == 0 {
+					rec.Outbound = true
+					rec.Path = model.OutboundPath("api.example.com", rec.Path)
+				}
+				if i%7 == 0 {
+					rec.GRPCCode = "Unavailable"
+					rec.MsgsSent = 2
+				}
+				s.Record(rec)
+			}
+		}()
+	}
+	writersWG.Wait()
+	close(done)
+	readers.Wait()
+
+	total := 0
+	for _, snap := range []map[string]map[string]*model.RouteStats{s.Snapshot(), s.OutboundSnapshot()} {
+		for _, paths := range snap {
+			for path, rs := range paths {
+				total += rs.TotalCount
+				if _, ok := rs.TotalStatus[999]; ok {
+					t.Errorf("%s: reader modification leaked into the store", path)
+				}
+			}
+		}
+	}
+	if total != writers*perWriter {
+		t.Errorf("recorded %d requests, want %d", total, writers*perWriter)
+	}
+	for _, c := range s.RouteCounts() {
+		if c.Routes > 4 {
+			t.Errorf("%s %s: %d routes over the limit of 4", c.Direction(), c.Method, c.Routes)
+		}
+	}
+}
+
+// readAll calls every read API and modifies what it returns.
+func readAll(s *Store) {
+	for _, snap := range []map[string]map[string]*model.RouteStats{s.Snapshot(), s.OutboundSnapshot()} {
+		for _, paths := range snap {
+			for _, rs := range paths {
+				rs.TotalStatus[999]++
+				rs.TotalCount++
+				if len(rs.TotalHistogram) > 0 {
+					rs.TotalHistogram[0]++
+				}
+				if len(rs.TotalExemplars) > 0 {
+					rs.TotalExemplars[0].TraceID = ""
+				}
+				for i := range rs.Buckets {
+					rs.Buckets[i].Status[999]++
+					if len(rs.Buckets[i].Latency) > 0 {
+						rs.Buckets[i].Latency[0].Weight++
+					}
+					if rs.Buckets[i].GRPCStatus != nil {
+						rs.Buckets[i].GRPCStatus["OK"]++
+					}
+				}
+			}
+		}
+	}
+	for _, path := range []string{"/a", "/b", model.OtherRoute} {
+		if points, ok := s.History(false, "GET", path); ok && len(points) > 0 {
+			points[0].Count++
+		}
+		if slowest, errors, ok := s.Samples(false, "GET", path); ok {
+			if len(slowest) > 0 {
+				slowest[0].Status = 0
+			}
+			if len(errors) > 0 {
+				errors[0].Status = 0
+			}
+		}
+	}
+	if counts := s.RouteCounts(); len(counts) > 0 {
+		counts[0].Routes++
+	}
+}
+
+// TestMethodsGrouped checks that made-up methods share one OTHER entry.
+func TestMethodsGrouped(t *testing.T) {
+	s := NewStore(Options{Retention: time.Minute, BucketWidth: time.Second})
+	for _, method := range []string{"GET", "GRPC", "PROPFIND", "X1", "X2", "get"} {
+		s.Record(model.RequestRecord{Timestamp: time.Now(), Status: 200, Method: method, Path: "/a"})
+	}
+
+	snap := s.Snapshot()
+	if len(snap) != 3 {
+		t.Errorf("methods %d, want GET, GRPC and OTHER", len(snap))
+	}
+	if got := snap[model.OtherMethod]["/a"]; got == nil || got.TotalCount != 4 {
+		t.Errorf("OTHER /a = %+v, want 4 requests", got)
+	}
+}
+
+// TestRecordBefore1970 records timestamps whose ring slot would be negative.
+func TestRecordBefore1970(t *testing.T) {
+	s := NewStore(Options{Retention: time.Minute, BucketWidth: time.Second, HistoryRetention: time.Minute, HistoryInterval: time.Second})
+	for _, at := range []time.Time{{}, time.Unix(-15, 0), time.Date(1969, 12, 31, 23, 59, 59, 0, time.UTC)} {
+		s.Record(model.RequestRecord{Timestamp: at, Duration: time.Millisecond, Status: 200, Method: "GET", Path: "/y"})
+	}
+	if got := s.Snapshot()["GET"]["/y"]; got == nil || got.TotalCount != 3 {
+		t.Errorf("GET /y = %+v, want 3 requests", got)
+	}
+}
+
+// baseline is the recording algorithm the store replaced, copied from the original
+// Monitor.CoreMiddleware: one mutex for every route, raw requests kept for 5 minutes
+// and trimmed with slices.IndexFunc and slices.Delete on every request.
+type baseline struct {
+	mu    sync.Mutex
+	stats map[string]map[string]*baselineRoute // method -> path -> stats
+}
+
+type baselineRecord struct {
+	Timestamp time.Time
+	Duration  time.Duration
+	Status    int
+	Method    string
+}
+
+type baselineRoute struct {
+	Recent []baselineRecord
+
+	TotalCount      int
+	TotalErrorCount int
+	TotalStatus     map[int]int
+	TotalTime       time.Duration
+	TotalMin        time.Duration
+	TotalMax        time.Duration
+	FirstSeen       time.Time
+	LastSeen        time.Time
+}
+
+func (m *baseline) CoreMiddleware(method string, path string, status int, start time.Time, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	methodStats, ok := m.stats[method]
+	if !ok {
+		methodStats = make(map[string]*baselineRoute)
+		m.stats[method] = methodStats
+	}
+
+	rs, ok := methodStats[path]
+	if !ok {
+		rs = &baselineRoute{
+			TotalStatus: make(map[int]int),
+			TotalMin:    elapsed,
+			TotalMax:    elapsed,
+			FirstSeen:   start,
+		}
+		methodStats[path] = rs
+	}
+
+	rs.Recent = append(rs.Recent, baselineRecord{
+		Timestamp: start,
+		Duration:  elapsed,
+		Status:    status,
+		Method:    method,
+	})
+
+	cutoff := time.Now().Add(-5 * time.Minute)
+	idx := slices.IndexFunc(rs.Recent, func(rec baselineRecord) bool {
+		return rec.Timestamp.After(cutoff)
+	})
+	if idx > 0 {
+		rs.Recent = slices.Delete(rs.Recent, 0, idx)
+	}
+
+	rs.TotalCount++
+	rs.TotalStatus[status]++
+	rs.TotalTime += elapsed
+	if elapsed < rs.TotalMin {
+		rs.TotalMin = elapsed
+	}
+	if elapsed > rs.TotalMax {
+		rs.TotalMax = elapsed
+	}
+	rs.LastSeen = time.Now()
+	if status >= 400 {
+		rs.TotalErrorCount++
+	}
+}
+
+// BenchmarkRecordParallel compares the sharded store with the algorithm it replaced,
+// for traffic to one hot route and spread over many routes. Use -cpu to vary parallelism.
+func BenchmarkRecordParallel(b *testing.B) {
+	routes := make([]string, 256)
+	for i := range routes {
+		routes[i] = fmt.Sprintf("/api/v1/items/%d", i)
+	}
+	stores := []struct {
+		name   string
+		record func() func(model.RequestRecord)
+	}{
+		{"sharded", func() func(model.RequestRecord) { return NewStore(Options{}).Record }},
+		{"baseline", func() func(model.RequestRecord) {
+			m := &baseline{stats: make(map[string]map[string]*baselineRoute)}
+			return func(rec model.RequestRecord) {
+				m.CoreMiddleware(rec.Method, rec.Path, rec.Status, rec.Timestamp, rec.Duration)
+			}
+		}},
+	}
+	traffic := []struct {
+		name  string
+		paths []string
+	}{
+		{"hot", routes[:1]},
+		{"many", routes},
+	}
+
+	for _, st := range stores {
+		for _, tr := range traffic {
+			b.Run(st.name+"/"+tr.name, func(b *testing.B) {
+				record := st.record()
+				var next atomic.Uint64
+				b.ReportAllocs()
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					i := int(next.Add(1)) * 7919
+					for pb.Next() {
+						i++
+						record(model.RequestRecord{
+							Timestamp: time.Now(),
+							Duration:  time.Duration(i%100) * time.Millisecond,
+							Status:    200,
+							Method:    "GET",
+							Path:      tr.paths[i%len(tr.paths)],
+						})
+					}
+				})
+			})
+		}
+	}
+}
@@ -2,11 +2,10 @@ package utility
 
 import (
 	"encoding/json"
-	"regexp"
 	"time"
 
-	"github.com/aurieli333/goapimon/config"
 	"github.com/aurieli333/goapimon/model"
+	"github.com/aurieli333/goapimon/normalize"
 
 	"github.com/influxdata/tdigest"
 )
@@ -14,6 +13,7 @@ import (
 type WindowStats struct {
 	Count     int
 	ErrCount  int
+	LongLived int // hijacked or streaming requests, not part of latency stats
 	ErrorRate float64
 	Status    map[int]int
 	Avg       float64
@@ -24,45 +24,86 @@ type WindowStats struct {
 	P90       float64
 	P95       float64
 	P99       float64
+
+	ReqBytes     int64 // request bytes received in the window
+	RespBytes    int64 // response bytes sent in the window
+	AvgReqBytes  float64
+	AvgRespBytes float64
+	P95RespBytes float64
+	Bandwidth    float64 // request + response bytes per second
+
+	GRPCStatus   map[string]int // gRPC code counts, nil for HTTP routes
+	MsgsReceived int64          // gRPC stream messages received in the window
+	MsgsSent     int64          // gRPC stream messages sent in the window
+
+	TransportErrors map[string]int // outbound failures without a response by kind, nil when none
 }
 
-func CalcWindowStats(recs []model.RequestRecord, window time.Duration, now time.Time) WindowStats {
+// CalcWindowStats merges all buckets overlapping (now-window, now].
+func CalcWindowStats(buckets []model.Bucket, window time.Duration, now time.Time) WindowStats {
 	stats := WindowStats{
 		Status: make(map[int]int),
 		Min:    -1,
 		Max:    -1,
 	}
 
-	if len(recs) == 0 {
+	if len(buckets) == 0 {
 		return stats
 	}
 
 	start := now.Add(-window)
+	covered := window
 	var sum time.Duration
 	var minDur time.Duration = 1<<63 - 1
 	var maxDur time.Duration = 0
 
 	td := tdigest.NewWithCompression(1000)
+	sizes := tdigest.NewWithCompression(100)
 
-	for _, rec := range recs {
-		if rec.Timestamp.After(start) {
-			stats.Count++
+	for _, b := range buckets {
+		if !b.End.After(start) || b.Start.After(now) || b.Count == 0 {
+			continue
+		}
+		// The oldest bucket may stick out of the window, widen the rate base accordingly
+		if b.Start.Before(start) && now.Sub(b.Start) > covered {
+			covered = now.Sub(b.Start)
+		}
 
-			if rec.Status >= 400 {
-				stats.ErrCount++
+		stats.Count += b.Count
+		stats.ErrCount += b.ErrCount
+		stats.LongLived += b.LongLived
+		for code, cnt := range b.Status {
+			stats.Status[code] += cnt
+		}
+		for code, cnt := range b.GRPCStatus {
+			if stats.GRPCStatus == nil {
+				stats.GRPCStatus = make(map[string]int)
 			}
-			stats.Status[rec.Status]++
+			stats.GRPCStatus[code] += cnt
+		}
+		for kind, cnt := range b.TransportErrors {
+			if stats.TransportErrors == nil {
+				stats.TransportErrors = make(map[string]int)
+			}
+			stats.TransportErrors[kind] += cnt
+		}
+		stats.MsgsReceived += b.MsgsReceived
+		stats.MsgsSent += b.MsgsSent
 
-			sum += rec.Duration
-			ms := float64(rec.Duration.Nanoseconds()) / 1_000_000.
-			td.Add(ms, 1)
+		sum += b.Sum
+		td.AddCentroidList(b.Latency)
+		stats.ReqBytes += b.ReqBytes
+		stats.RespBytes += b.RespBytes
+		sizes.AddCentroidList(b.RespSize)
 
-			if rec.Duration < minDur {
-				minDur = rec.Duration
-			}
-			if rec.Duration > maxDur {
-				maxDur = rec.Duration
-			}
+		if b.Count == b.LongLived {
+			continue
+		}
+		if b.Min < minDur {
+			minDur = b.Min
+		}
+		if b.Max > maxDur {
+			maxDur = b.Max
 		}
 	}
 
@@ -70,19 +111,41 @@ func CalcWindowStats(recs []model.RequestRecord, window time.Duration, now time.
 		return stats
 	}
 
-	stats.Avg = float64(sum.Nanoseconds()) / 1_000_000. / float64(stats.Count)
-	stats.Min = float64(sum.Nanoseconds()) / 1_000_000.
-	stats.Max = float64(sum.Nanoseconds()) / 1_000_000.
-	stats.RPS = float64(stats.Count) / window.Seconds()
+	stats.RPS = float64(stats.Count) / covered.Seconds()
 	stats.ErrorRate = float64(stats.ErrCount) / float64(stats.Count) * 100
 
-	stats.P50 = td.Quantile(0.50)
-	stats.P90 = td.Quantile(0.90)
-	stats.P95 = td.Quantile(0.95)
-	stats.P99 = td.Quantile(0.99)
+	if timed := stats.Count - stats.LongLived; timed > 0 {
+		stats.Avg = float64(sum.Nanoseconds()) / 1_000_000. / float64(timed)
+		stats.Min = float64(minDur.Nanoseconds()) / 1_000_000.
+		stats.Max = float64(maxDur.Nanoseconds()) / 1_000_000.
+
+		stats.P50 = td.Quantile(0.50)
+		stats.P90 = td.Quantile(0.90)
+		stats.P95 = td.Quantile(0.95)
+		stats.P99 = td.Quantile(0.99)
+	} else {
+		stats.P50, stats.P90, stats.P95, stats.P99 = -1, -1, -1, -1
+	}
+
+	stats.AvgReqBytes = float64(stats.ReqBytes) / float64(stats.Count)
+	stats.AvgRespBytes = float64(stats.RespBytes) / float64(stats.Count)
+	if sizes.Count() > 0 {
+		stats.P95RespBytes = sizes.Quantile(0.95)
+	}
+	stats.Bandwidth = float64(stats.ReqBytes+stats.RespBytes) / covered.Seconds()
 	return stats
 }
 
+// Quantiles returns P50, P90, P95 and P99 of a latency digest, or -1 when it is empty.
+func Quantiles(cl tdigest.CentroidList) (p50, p90, p95, p99 float64) {
+	if len(cl) == 0 {
+		return -1, -1, -1, -1
+	}
+	td := tdigest.NewWithCompression(1000)
+	td.AddCentroidList(cl)
+	return td.Quantile(0.50), td.Quantile(0.90), td.Quantile(0.95), td.Quantile(0.99)
+}
+
 // Helper to marshal JSON or panic
 func MustJSON(v interface{}) []byte {
 	b, err := json.Marshal(v)
@@ -92,18 +155,7 @@ func MustJSON(v interface{}) []byte {
 	return b
 }
 
-func IsInternalPath(path string) bool {
-	_, ok := config.InternalPaths[path]
-	return ok
-}
-
-var (
-	uuidRegex = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
-	intRegex  = regexp.MustCompile(`/\d+`)
-)
-
+// NormalizePath normalizes with the built-in rules only, adapters use Monitor.NormalizePath
 func NormalizePath(path string) string {
-	path = uuidRegex.ReplaceAllString(path, ":id")
-	path = intRegex.ReplaceAllString(path, "/:id")
-	return path
+	return normalize.Default.Normalize(path)
 }